@@ -0,0 +1,166 @@
+package wiktionary
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// PageResponse holds what the API returned for a page - the wikitext, and the details of
+// the page and revision it came from
+type PageResponse struct {
+	Title      string        // the title of the page, after any normalization and redirects
+	PageId     int64         // the MediaWiki page ID
+	RevId      int64         // the ID of the revision the wikitext is from
+	Timestamp  time.Time     // when the revision was saved
+	Wikitext   string        // the raw wikitext of the page
+	Normalized []TitleChange // how the requested title was normalized, e.g. "red_dwarf" to "red dwarf"
+	Redirects  []TitleChange // the redirects followed to reach the page
+	Warnings   []APIWarning  // any warnings the API returned along with the page
+}
+
+func (p *PageResponse) revision() *Revision {
+	// the revision details for a LanguageWord, or nil if the source didn't report them
+	if p.RevId == 0 {
+		return nil
+	}
+	return &Revision{PageId: p.PageId, RevisionId: p.RevId, Timestamp: p.Timestamp}
+}
+
+// ParseResponse holds what the API returned when rendering text
+type ParseResponse struct {
+	Title    string       // the title the text was rendered as
+	Text     string       // the rendered HTML
+	Warnings []APIWarning // any warnings the API returned along with the HTML
+}
+
+// TitleChange records a title which the API replaced with another
+type TitleChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// APIWarning is a warning returned by the API - Module is the part of the API which raised it,
+// e.g. "main" for an unrecognized parameter
+type APIWarning struct {
+	Module string
+	Text   string
+}
+
+// the parts shared by every formatversion=2 API response
+type apiResponse struct {
+	Error *struct {
+		Code string `json:"code"`
+		Info string `json:"info"`
+	} `json:"error"`
+	Warnings map[string]struct {
+		Warnings string `json:"warnings"`
+	} `json:"warnings"`
+}
+
+func (r apiResponse) apiError() error {
+	// return an APIError if the response holds an error object, otherwise nil
+	if r.Error == nil {
+		return nil
+	}
+	return &APIError{Code: r.Error.Code, Info: r.Error.Info}
+}
+
+func (r apiResponse) apiWarnings() []APIWarning {
+	// return the warnings in module order, so they come out the same way each time
+	var warnings []APIWarning
+	for module, w := range r.Warnings {
+		warnings = append(warnings, APIWarning{Module: module, Text: w.Warnings})
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Module < warnings[j].Module
+	})
+	return warnings
+}
+
+func decodePageResponse(body []byte, word string, title string) (*PageResponse, error) {
+	// decode an action=query&prop=revisions response holding a single page
+	var resp struct {
+		apiResponse
+		Query struct {
+			Normalized []TitleChange   `json:"normalized"`
+			Redirects  []TitleChange   `json:"redirects"`
+			BadRevIds  json.RawMessage `json:"badrevids"`
+			Pages      []struct {
+				PageId        int64  `json:"pageid"`
+				Title         string `json:"title"`
+				Missing       bool   `json:"missing"`
+				Invalid       bool   `json:"invalid"`
+				InvalidReason string `json:"invalidreason"`
+				Revisions     []struct {
+					RevId     int64     `json:"revid"`
+					Timestamp time.Time `json:"timestamp"`
+					Slots     struct {
+						Main struct {
+							Content string `json:"content"`
+						} `json:"main"`
+					} `json:"slots"`
+				} `json:"revisions"`
+			} `json:"pages"`
+		} `json:"query"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if err := resp.apiError(); err != nil {
+		if err.(*APIError).Code == "missingtitle" {
+			return nil, &PageNotFoundError{Word: word, Title: title, Err: err}
+		}
+		return nil, err
+	}
+
+	// as is a revision which doesn't exist
+	if len(resp.Query.BadRevIds) > 2 {
+		return nil, &APIError{Code: "nosuchrevid", Info: "There is no revision with the ID requested."}
+	}
+
+	// a missing page is reported on the page itself, rather than as an error
+	if len(resp.Query.Pages) == 0 {
+		return nil, &PageNotFoundError{Word: word, Title: title}
+	}
+	page := resp.Query.Pages[0]
+	if page.Invalid {
+		return nil, &APIError{Code: "invalidtitle", Info: page.InvalidReason}
+	}
+	if page.Missing || len(page.Revisions) == 0 {
+		return nil, &PageNotFoundError{Word: word, Title: title}
+	}
+
+	return &PageResponse{
+		Title:      page.Title,
+		PageId:     page.PageId,
+		RevId:      page.Revisions[0].RevId,
+		Timestamp:  page.Revisions[0].Timestamp,
+		Wikitext:   page.Revisions[0].Slots.Main.Content,
+		Normalized: resp.Query.Normalized,
+		Redirects:  resp.Query.Redirects,
+		Warnings:   resp.apiWarnings(),
+	}, nil
+}
+
+func decodeParseResponse(body []byte) (*ParseResponse, error) {
+	// decode an action=parse&prop=text response
+	var resp struct {
+		apiResponse
+		Parse struct {
+			Title string `json:"title"`
+			Text  string `json:"text"`
+		} `json:"parse"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if err := resp.apiError(); err != nil {
+		return nil, err
+	}
+	return &ParseResponse{
+		Title:    resp.Parse.Title,
+		Text:     resp.Parse.Text,
+		Warnings: resp.apiWarnings(),
+	}, nil
+}
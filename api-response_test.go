@@ -0,0 +1,101 @@
+package wiktionary
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodePageResponse(t *testing.T) {
+	body := `{"batchcomplete":true,` +
+		`"warnings":{"main":{"warnings":"Unrecognized parameter: foo."},"revisions":{"warnings":"Because \"rvslots\" was not specified..."}},` +
+		`"query":{"normalized":[{"fromencoded":false,"from":"red_giant","to":"red giant"}],` +
+		`"redirects":[{"from":"red giant","to":"red-giant"}],` +
+		`"pages":[{"pageid":3654,"ns":0,"title":"red-giant","revisions":[{"revid":75123456,"parentid":75000000,` +
+		`"slots":{"main":{"contentmodel":"wikitext","contentformat":"text/x-wiki",` +
+		`"content":"{{IPA|en|/\u0279\u025bd/|[\u027b\u02b7\u025b\u02d1d\u0325]}}\n{{m|ae|\ud802\udf00}}"}}}]}]}}`
+	page, err := decodePageResponse([]byte(body), "red_giant", "red_giant")
+	if err != nil {
+		t.Fatalf(`Error from decodePageResponse: %q`, err)
+	}
+
+	// escapes, including surrogate pairs for scripts such as Avestan, are decoded
+	expected := "{{IPA|en|/ɹɛd/|[ɻʷɛˑd̥]}}\n{{m|ae|𐬀}}"
+	if page.Wikitext != expected {
+		t.Fatalf(`page.Wikitext: expected %q, got %q`, expected, page.Wikitext)
+	}
+	if page.Title != "red-giant" || page.PageId != 3654 || page.RevId != 75123456 {
+		t.Fatalf(`decodePageResponse: expected red-giant, 3654, 75123456, got %v, %v, %v`, page.Title, page.PageId, page.RevId)
+	}
+	if !reflect.DeepEqual(page.Normalized, []TitleChange{{From: "red_giant", To: "red giant"}}) {
+		t.Fatalf(`page.Normalized: got %v`, page.Normalized)
+	}
+	if !reflect.DeepEqual(page.Redirects, []TitleChange{{From: "red giant", To: "red-giant"}}) {
+		t.Fatalf(`page.Redirects: got %v`, page.Redirects)
+	}
+	expectedWarnings := []APIWarning{
+		{Module: "main", Text: "Unrecognized parameter: foo."},
+		{Module: "revisions", Text: `Because "rvslots" was not specified...`},
+	}
+	if !reflect.DeepEqual(page.Warnings, expectedWarnings) {
+		t.Fatalf(`page.Warnings: expected %v, got %v`, expectedWarnings, page.Warnings)
+	}
+}
+
+func TestDecodePageResponseErrors(t *testing.T) {
+	body := `{"batchcomplete":true,"query":{"pages":[{"ns":0,"title":"Xyzzy","missing":true}]}}`
+	_, err := decodePageResponse([]byte(body), "xyzzy", "xyzzy")
+	var notFound *PageNotFoundError
+	if !errors.As(err, &notFound) || notFound.Title != "xyzzy" {
+		t.Fatalf(`decodePageResponse: expected a PageNotFoundError, got %q`, err)
+	}
+
+	body = `{"batchcomplete":true,"query":{"pages":[{"title":"a[b]","invalidreason":"The requested page title contains invalid characters: \"[\".","invalid":true}]}}`
+	_, err = decodePageResponse([]byte(body), "a[b]", "a[b]")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "invalidtitle" {
+		t.Fatalf(`decodePageResponse: expected an APIError with code "invalidtitle", got %q`, err)
+	}
+
+	body = `{"error":{"code":"missingtitle","info":"The page you specified doesn't exist.","docref":"See https://en.wiktionary.org/w/api.php for API usage."},"servedby":"mw1234"}`
+	_, err = decodePageResponse([]byte(body), "xyzzy", "xyzzy")
+	if !errors.As(err, &notFound) || !errors.As(err, &apiErr) || apiErr.Code != "missingtitle" {
+		t.Fatalf(`decodePageResponse: expected a PageNotFoundError wrapping an APIError, got %q`, err)
+	}
+
+	if _, err := decodePageResponse([]byte(`<html>`), "xyzzy", "xyzzy"); err == nil {
+		t.Fatalf(`decodePageResponse: expected an error for a response which isn't JSON`)
+	}
+}
+
+func TestDecodeParseResponse(t *testing.T) {
+	body := `{"warnings":{"parse":{"warnings":"Unrecognized value for parameter \"prop\": foo."}},` +
+		`"parse":{"title":"red","pageid":0,"text":"<div class=\"mw-parser-output\"><p>red\n</p></div>"}}`
+	parsed, err := decodeParseResponse([]byte(body))
+	if err != nil {
+		t.Fatalf(`Error from decodeParseResponse: %q`, err)
+	}
+	expected := "<div class=\"mw-parser-output\"><p>red\n</p></div>"
+	if parsed.Text != expected || parsed.Title != "red" {
+		t.Fatalf(`decodeParseResponse: expected %q for red, got %q for %v`, expected, parsed.Text, parsed.Title)
+	}
+	if len(parsed.Warnings) != 1 || parsed.Warnings[0].Module != "parse" {
+		t.Fatalf(`parsed.Warnings: got %v`, parsed.Warnings)
+	}
+}
+
+func TestApiSourceGetPage(t *testing.T) {
+	server, requests := newTestApi(t, map[string]string{"green": testWikitext})
+	page, err := ApiSource{BaseUrl: server.URL}.GetPage(context.Background(), "green", "en")
+	if err != nil {
+		t.Fatalf(`Error from ApiSource.GetPage: %q`, err)
+	}
+	if page.Wikitext != testWikitext || page.PageId != 1 || page.RevId != 100 {
+		t.Fatalf(`ApiSource.GetPage: got page %v, revision %v`, page.PageId, page.RevId)
+	}
+	form := (*requests)[0].Form
+	if form.Get("formatversion") != "2" || form.Get("action") != "query" {
+		t.Fatalf(`ApiSource.GetPage: expected a formatversion=2 query, got %v`, form)
+	}
+}
@@ -0,0 +1,116 @@
+package wiktionary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// texts longer than this are POSTed to the API rather than sent in the URL
+const maxGetTextLength = 2000
+
+// the most text we will send to the API in a single batch - larger pages are split into several
+const maxBatchLength = 200000
+
+// a renderBatch holds every line of a page which needs rendering by Wiktionary, so they can be
+// rendered in a single API call rather than one call per line
+type renderBatch struct {
+	collecting bool
+	pending    []string
+	seen       map[string]bool
+	rendered   map[string]string
+}
+
+func (b *renderBatch) add(text string) {
+	if b.seen[text] {
+		return
+	}
+	b.seen[text] = true
+	b.pending = append(b.pending, text)
+}
+
+func prepareRenderBatch(ctx context.Context, word string, langCode string, sections []Section, options WiktionaryOptions) *renderBatch {
+	// only sources which can render templates are worth batching
+	renderer, ok := getSource(options).(TextRenderer)
+	if !ok {
+		return nil
+	}
+	batch := &renderBatch{
+		collecting: true,
+		seen:       make(map[string]bool),
+		rendered:   make(map[string]string),
+	}
+
+	// make a first pass over the sections, which collects every text the parsers ask to be
+	// rendered rather than rendering them - the results of this pass are thrown away
+	options.batch = batch
+	parseSections(ctx, word, langCode, sections, options)
+	batch.collecting = false
+
+	// now render the texts in as few calls as possible
+	// if a batch fails, those lines will just be rendered one at a time by the real pass
+	var texts []string
+	length := 0
+	for _, text := range batch.pending {
+		if length+len(text) > maxBatchLength && len(texts) > 0 {
+			renderTexts(ctx, renderer, texts, word, langCode, batch.rendered)
+			texts = nil
+			length = 0
+		}
+		texts = append(texts, text)
+		length += len(text)
+	}
+	if len(texts) > 0 {
+		renderTexts(ctx, renderer, texts, word, langCode, batch.rendered)
+	}
+
+	// cache each text separately, so they can be found whichever way they are rendered next time
+	if cache := options.Cache; cache != nil {
+		title := getPageTitle(word, langCode)
+		for text, html := range batch.rendered {
+			cache.PutText(text, title, html)
+		}
+	}
+	return batch
+}
+
+func renderTexts(ctx context.Context, renderer TextRenderer, texts []string, word string, langCode string, rendered map[string]string) error {
+	// join the texts into a single block, each one preceded by a unique marker paragraph
+	// so that we can split the rendered HTML back into the separate texts
+	marker := "WIKTIONARYBATCH" + strconv.FormatInt(rand.Int63(), 36)
+	var block []string
+	for i, text := range texts {
+		block = append(block, fmt.Sprintf("%s-%d", marker, i), text)
+	}
+	html, err := renderer.RenderText(ctx, strings.Join(block, "\n\n"), word, langCode)
+	if err != nil {
+		return err
+	}
+
+	// each marker is rendered as a paragraph of its own
+	re := regexp.MustCompile(`<p>` + marker + `-(\d+)\s*</p>\s*`)
+	locs := re.FindAllStringSubmatchIndex(html, -1)
+	if len(locs) != len(texts) {
+		// something in one of the texts has swallowed a marker (e.g. an unclosed tag)
+		return errors.New("batch rendering: markers missing from the rendered HTML")
+	}
+	for i, loc := range locs {
+		if html[loc[2]:loc[3]] != strconv.Itoa(i) {
+			return errors.New("batch rendering: markers out of order in the rendered HTML")
+		}
+	}
+
+	// the HTML for each text runs from the end of its marker to the start of the next one
+	for i, loc := range locs {
+		end := len(html)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		rendered[texts[i]] = html[loc[1]:end]
+	}
+	return nil
+}
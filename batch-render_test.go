@@ -0,0 +1,116 @@
+package wiktionary
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// a stand-in for the action=parse API, which renders each block of text as a paragraph
+// (or a list, for lines beginning with *) and expands templates to their name in capitals
+type testRenderer struct {
+	MapSource
+	calls int
+}
+
+func (r *testRenderer) RenderText(ctx context.Context, text string, word string, langCode string) (string, error) {
+	r.calls++
+	html := `<div class="mw-parser-output">`
+	for _, block := range strings.Split(text, "\n\n") {
+		for strings.Contains(block, "{{") {
+			start := strings.Index(block, "{{")
+			end := strings.Index(block, "}}")
+			name := strings.Split(block[start+2:end], "|")[0]
+			block = block[:start] + "<i>" + strings.ToUpper(name) + "</i>" + block[end+2:]
+		}
+		if strings.HasPrefix(block, "*") {
+			html += "<ul><li>" + strings.TrimSpace(block[1:]) + "</li></ul>\n"
+		} else {
+			html += "<p>" + block + "\n</p>\n"
+		}
+	}
+	return html + "<!-- limit report -->\n</div>", nil
+}
+
+const testBatchWikitext = `==English==
+
+===Pronunciation===
+* {{IPA|en|/ɡɹiːn/}}
+* {{rhymes|en|iːn|s=1}}
+
+===Etymology===
+From {{inh|en|enm|grene}}, {{cog|grc|χλωρός}}.
+
+===Adjective===
+{{en-adj|er}}
+
+# Having green as its color.
+# {{lb|en|figuratively}} {{non-gloss|Describing a person}}.
+
+====Descendants====
+* {{desctree|sw|grini}}`
+
+func TestBatchRendering(t *testing.T) {
+	chdirTemp(t)
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.RequiredLanguages = AllLanguages
+
+	// render the page one line at a time
+	single := &testRenderer{MapSource: MapSource{"green": testBatchWikitext}}
+	options.Source = single
+	expected, err := GetWordWithOptions("green", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+
+	// then in a single batch
+	batched := &testRenderer{MapSource: MapSource{"green": testBatchWikitext}}
+	options.Source = batched
+	options.BatchRendering = true
+	lw, err := GetWordWithOptions("green", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+
+	if single.calls < 5 {
+		t.Fatalf(`single rendering: expected at least 5 calls, got %v`, single.calls)
+	}
+	if batched.calls != 1 {
+		t.Fatalf(`batch rendering: expected 1 call, got %v`, batched.calls)
+	}
+
+	if !reflect.DeepEqual(lw, expected) {
+		t.Fatalf(`batch rendering: expected %v, got %v`, expected, lw)
+	}
+	if lw.Etymologies[0].Parts[0].Headword != "EN-ADJ" {
+		t.Fatalf(`batch rendering: expected headword %q, got %q`, "EN-ADJ", lw.Etymologies[0].Parts[0].Headword)
+	}
+}
+
+// a renderer which loses the markers, as an unclosed tag in one of the texts might
+type swallowingRenderer struct{}
+
+func (r swallowingRenderer) RenderText(ctx context.Context, text string, word string, langCode string) (string, error) {
+	return `<div class="mw-parser-output"><p>` + text + "\n</p></div>", nil
+}
+
+func TestRenderTexts(t *testing.T) {
+	// if a marker goes missing, nothing from the batch can be trusted
+	renderer := &testRenderer{}
+	rendered := make(map[string]string)
+	err := renderTexts(context.Background(), renderer, []string{"{{a}}", "* {{b}}"}, "green", "en", rendered)
+	if err != nil || len(rendered) != 2 {
+		t.Fatalf(`renderTexts: expected 2 rendered texts, got %v (%v)`, len(rendered), err)
+	}
+	if !strings.Contains(rendered["* {{b}}"], "<li>") {
+		t.Fatalf(`renderTexts: expected a list item, got %q`, rendered["* {{b}}"])
+	}
+
+	rendered = make(map[string]string)
+	err = renderTexts(context.Background(), swallowingRenderer{}, []string{"{{a}}", "* {{b}}"}, "green", "en", rendered)
+	if err == nil || len(rendered) != 0 {
+		t.Fatalf(`renderTexts: expected an error for HTML which has lost its markers`)
+	}
+}
@@ -0,0 +1,153 @@
+package wiktionary
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// a Client holds the configuration used for lookups - the package-level functions such as
+// GetWord use DefaultClient, which writes debug files to the working directory as before
+type Client struct {
+	BaseUrl    string       // the MediaWiki API to call - defaults to en.wiktionary.org
+	HttpClient *http.Client // defaults to http.DefaultClient
+	UserAgent  string       // defaults to identifying this library, as Wikimedia asks
+	Cache      *DiskCache   // if set, pages and rendered text are cached here
+	OutputDir  string       // if set, a JSON and a wikitext file are written here for each word
+	Transport  *Transport   // rate limiting, retries and timeouts - a shared default if nil
+	MediaUrl   string       // where audio files are downloaded from - defaults to Wikimedia Commons
+}
+
+var DefaultClient = &Client{OutputDir: "."}
+
+// each lookup has a Context variant, which stops any remaining calls to Wiktionary once the
+// context is cancelled or its deadline passes, and returns a *CanceledError
+
+func (c *Client) GetWord(word string, langCode string) (LanguageWord, error) {
+	return c.GetWordContext(context.Background(), word, langCode)
+}
+
+func (c *Client) GetWordContext(ctx context.Context, word string, langCode string) (LanguageWord, error) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.RequiredLanguages = AllLanguages
+	return c.GetWordWithOptionsContext(ctx, word, langCode, options)
+}
+
+func (c *Client) GetWordWithOptions(word string, langCode string, options WiktionaryOptions) (LanguageWord, error) {
+	return c.GetWordWithOptionsContext(context.Background(), word, langCode, options)
+}
+
+func (c *Client) GetWordWithOptionsContext(ctx context.Context, word string, langCode string, options WiktionaryOptions) (LanguageWord, error) {
+	lw, err := processWord(ctx, word, langCode, c.getOptions(options))
+	return lw, err
+}
+
+func (c *Client) GetAllLanguages(word string) (map[string]LanguageWord, error) {
+	return c.GetAllLanguagesContext(context.Background(), word)
+}
+
+func (c *Client) GetAllLanguagesContext(ctx context.Context, word string) (map[string]LanguageWord, error) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.RequiredLanguages = AllLanguages
+	return c.GetAllLanguagesWithOptionsContext(ctx, word, options)
+}
+
+func (c *Client) GetAllLanguagesWithOptions(word string, options WiktionaryOptions) (map[string]LanguageWord, error) {
+	return c.GetAllLanguagesWithOptionsContext(context.Background(), word, options)
+}
+
+func (c *Client) GetAllLanguagesWithOptionsContext(ctx context.Context, word string, options WiktionaryOptions) (map[string]LanguageWord, error) {
+	// every language with an entry on the word's page, keyed by language code
+	return processAllLanguages(ctx, word, c.getOptions(options))
+}
+
+func (c *Client) GetMeaning(word string, langCode string) (string, error) {
+	return c.GetMeaningContext(context.Background(), word, langCode)
+}
+
+func (c *Client) GetMeaningContext(ctx context.Context, word string, langCode string) (string, error) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_Parts | Sec_Meanings
+	options.RequiredLanguages = AllLanguages
+	lw, err := c.GetWordWithOptionsContext(ctx, word, langCode, options)
+	return lw.Meaning, err
+}
+
+func (c *Client) GetTranslations(word string, langCode string, requiredLanguages []string) ([]TranslatedWord, error) {
+	return c.GetTranslationsContext(context.Background(), word, langCode, requiredLanguages)
+}
+
+func (c *Client) GetTranslationsContext(ctx context.Context, word string, langCode string, requiredLanguages []string) ([]TranslatedWord, error) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_Parts | Sec_Translations
+	options.RequiredLanguages = requiredLanguages
+	lw, err := c.GetWordWithOptionsContext(ctx, word, langCode, options)
+	if err != nil {
+		return nil, err
+	}
+	var tr []TranslatedWord
+	// iterate across all etymologies and parts to find the translations - if some were on another
+	// page which couldn't be read, the error is returned along with those there are
+	for _, etym := range lw.Etymologies {
+		for _, part := range etym.Parts {
+			tr = append(tr, part.Translations...)
+			for _, table := range part.TranslationTables {
+				if table.err != nil && err == nil {
+					err = table.err
+				}
+			}
+		}
+	}
+	return tr, err
+}
+
+func (c *Client) GetIpaPronunciation(word string, langCode string) (string, error) {
+	return c.GetIpaPronunciationContext(context.Background(), word, langCode)
+}
+
+func (c *Client) GetIpaPronunciationContext(ctx context.Context, word string, langCode string) (string, error) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_IPA
+	options.RequiredLanguages = AllLanguages
+	lw, err := c.GetWordWithOptionsContext(ctx, word, langCode, options)
+	if err != nil {
+		return "", err
+	}
+	if lw.Ipa == "" {
+		return "", errors.New("no IPA found")
+	}
+	return lw.Ipa, nil
+}
+
+func (c *Client) GetEtymologyTree(word string, langCode string, languages []string) TreeNode {
+	etym, _ := c.GetEtymologyTreeContext(context.Background(), word, langCode, languages)
+	return etym
+}
+
+func (c *Client) GetEtymologyTreeContext(ctx context.Context, word string, langCode string, languages []string) (TreeNode, error) {
+	// get a full etymology tree containing ancestor words
+	etym, err := c.getEtymologyTree(ctx, word, langCode, languages)
+	if err != nil {
+		return etym, err
+	}
+	return etym, nil // TODO
+}
+
+func (c *Client) getOptions(options WiktionaryOptions) WiktionaryOptions {
+	// fill in anything the options don't specify from the client's configuration
+	if options.Source == nil {
+		options.Source = ApiSource{
+			BaseUrl:    c.BaseUrl,
+			HttpClient: c.HttpClient,
+			UserAgent:  c.UserAgent,
+			Transport:  c.Transport,
+		}
+	}
+	if options.Cache == nil {
+		options.Cache = c.Cache
+	}
+	options.outputDir = c.OutputDir
+	return options
+}
@@ -0,0 +1,322 @@
+package wiktionary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a stand-in for the MediaWiki API, serving the given pages and rendering any text
+// as a single paragraph with its templates replaced by their names
+// pages are served as revision 100, except those keyed as e.g. "green@90", which can
+// only be fetched by their revision ID
+func newTestApi(t *testing.T, pages map[string]string) (*httptest.Server, *[]*http.Request) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requests = append(requests, r)
+		w.Header().Set("Content-Type", "application/json")
+		page, revId, timestamp := r.Form.Get("titles"), "100", "2023-10-01T12:00:00Z"
+		if revIds := r.Form.Get("revids"); revIds != "" {
+			revId, timestamp = revIds, "2023-09-01T12:00:00Z"
+			for key := range pages {
+				if strings.HasSuffix(key, "@"+revIds) {
+					page = strings.TrimSuffix(key, "@"+revIds)
+				}
+			}
+			if page == "" {
+				w.Write([]byte(`{"batchcomplete":true,"query":{"badrevids":{"` + revIds + `":{"revid":` + revIds + `,"missing":true}}}}`))
+				return
+			}
+		}
+		if page != "" {
+			key := page
+			if revId != "100" {
+				key += "@" + revId
+			}
+			wikitext, ok := pages[key]
+			if !ok {
+				w.Write([]byte(`{"batchcomplete":true,"query":{"pages":[{"ns":0,"title":` + strconv.Quote(page) + `,"missing":true}]}}`))
+				return
+			}
+			var resp struct {
+				Query struct {
+					Pages []interface{} `json:"pages"`
+				} `json:"query"`
+			}
+			id, _ := strconv.Atoi(revId)
+			resp.Query.Pages = []interface{}{map[string]interface{}{
+				"pageid": 1,
+				"title":  page,
+				"revisions": []interface{}{map[string]interface{}{
+					"revid":     id,
+					"timestamp": timestamp,
+					"slots":     map[string]interface{}{"main": map[string]interface{}{"content": wikitext}},
+				}},
+			}}
+			b, _ := json.Marshal(resp)
+			w.Write(b)
+			return
+		}
+		text := r.Form.Get("text")
+		for strings.Contains(text, "{{") {
+			start := strings.Index(text, "{{")
+			end := strings.Index(text, "}}")
+			name := strings.Split(text[start+2:end], "|")[0]
+			text = text[:start] + "<b>" + name + "</b>" + text[end+2:]
+		}
+		var resp struct {
+			Parse struct {
+				Title string `json:"title"`
+				Text  string `json:"text"`
+			} `json:"parse"`
+		}
+		resp.Parse.Title = r.Form.Get("title")
+		resp.Parse.Text = `<div class="mw-parser-output"><p>` + text + "\n</p>\n<!-- limit report -->\n</div>"
+		b, _ := json.Marshal(resp)
+		w.Write(b)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestClient(t *testing.T) {
+	server, requests := newTestApi(t, map[string]string{"green": testWikitext})
+	dir := t.TempDir()
+	client := &Client{
+		BaseUrl:   server.URL,
+		UserAgent: "test-agent",
+		OutputDir: dir,
+	}
+
+	lw, err := client.GetWord("green", "en")
+	if err != nil {
+		t.Fatalf(`Error from Client.GetWord: %q`, err)
+	}
+	expected := "Having green as its color."
+	if lw.Meaning != expected {
+		t.Fatalf(`lw.Meaning: expected %q, got %q`, expected, lw.Meaning)
+	}
+	// the headword template can only be rendered by the API
+	expected = "en-adj"
+	if lw.Etymologies[0].Parts[0].Headword != expected {
+		t.Fatalf(`lw.Etymologies[0].Parts[0].Headword: expected %q, got %q`, expected, lw.Etymologies[0].Parts[0].Headword)
+	}
+	for _, r := range *requests {
+		if r.UserAgent() != "test-agent" {
+			t.Fatalf(`Client: expected User-Agent %q, got %q`, "test-agent", r.UserAgent())
+		}
+	}
+
+	// the debug files go to the output directory
+	for _, fileName := range []string{"en-green.json", "en-green.wikitext"} {
+		if _, err := os.Stat(filepath.Join(dir, fileName)); err != nil {
+			t.Fatalf(`Client: expected %v to be written: %q`, fileName, err)
+		}
+	}
+
+	meaning, err := client.GetMeaning("green", "nl")
+	if err != nil {
+		t.Fatalf(`Error from Client.GetMeaning: %q`, err)
+	}
+	if meaning != "A Dutch word." {
+		t.Fatalf(`Client.GetMeaning: expected %q, got %q`, "A Dutch word.", meaning)
+	}
+
+	if _, err := client.GetWord("blue", "en"); err == nil {
+		t.Fatalf(`Client.GetWord: expected an error for a missing page`)
+	}
+}
+
+func TestClientDefaults(t *testing.T) {
+	// without an output directory, no files are written
+	chdirTemp(t)
+	server, requests := newTestApi(t, map[string]string{"green": testWikitext})
+	client := &Client{BaseUrl: server.URL}
+	if _, err := client.GetWord("green", "en"); err != nil {
+		t.Fatalf(`Error from Client.GetWord: %q`, err)
+	}
+	files, _ := os.ReadDir(".")
+	if len(files) != 0 {
+		t.Fatalf(`Client: expected no files to be written, got %v`, len(files))
+	}
+	if ua := (*requests)[0].UserAgent(); ua != defaultUserAgent {
+		t.Fatalf(`Client: expected User-Agent %q, got %q`, defaultUserAgent, ua)
+	}
+}
+
+func TestClientContext(t *testing.T) {
+	server, requests := newTestApi(t, map[string]string{"green": testBatchWikitext})
+	client := &Client{BaseUrl: server.URL}
+
+	// a context which is already done makes no requests at all
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.GetWordContext(ctx, "green", "en")
+	var canceled *CanceledError
+	if !errors.As(err, &canceled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf(`Client.GetWordContext: expected a CanceledError, got %q`, err)
+	}
+	if len(*requests) != 0 {
+		t.Fatalf(`Client.GetWordContext: expected no requests, got %v`, len(*requests))
+	}
+
+	// cancelling part way through stops the remaining template rendering
+	ctx, cancel = context.WithCancel(context.Background())
+	source := &cancelingRenderer{MapSource: MapSource{"green": testBatchWikitext}, cancel: cancel}
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.RequiredLanguages = AllLanguages
+	options.Source = source
+	_, err = client.GetWordWithOptionsContext(ctx, "green", "en", options)
+	if !errors.As(err, &canceled) || canceled.Word != "green" {
+		t.Fatalf(`Client.GetWordWithOptionsContext: expected a CanceledError for green, got %q`, err)
+	}
+	if source.calls != 1 {
+		t.Fatalf(`Client.GetWordWithOptionsContext: expected 1 render call, got %v`, source.calls)
+	}
+
+	// and deadlines are reported the same way
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	_, err = client.GetTranslationsContext(ctx, "green", "en", AllLanguages)
+	if !errors.As(err, &canceled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf(`Client.GetTranslationsContext: expected a CanceledError, got %q`, err)
+	}
+}
+
+// a renderer which cancels the lookup the first time it is called
+type cancelingRenderer struct {
+	MapSource
+	cancel func()
+	calls  int
+}
+
+func (r *cancelingRenderer) RenderText(ctx context.Context, text string, word string, langCode string) (string, error) {
+	r.calls++
+	r.cancel()
+	return "", ctx.Err()
+}
+
+func TestClientRevision(t *testing.T) {
+	oldWikitext := strings.Replace(testWikitext, "Having green as its color.", "Of a green colour.", 1)
+	server, requests := newTestApi(t, map[string]string{"green": testWikitext, "green@90": oldWikitext, "blue@80": testWikitext})
+	cache := &DiskCache{Dir: t.TempDir()}
+	client := &Client{BaseUrl: server.URL, Cache: cache}
+
+	// the latest revision is recorded in the entry
+	lw, err := client.GetWord("green", "en")
+	if err != nil {
+		t.Fatalf(`Error from Client.GetWord: %q`, err)
+	}
+	expected := Revision{PageId: 1, RevisionId: 100, Timestamp: time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)}
+	if lw.Revision == nil || *lw.Revision != expected {
+		t.Fatalf(`lw.Revision: expected %v, got %v`, expected, lw.Revision)
+	}
+
+	// an older revision can be pinned, and compared with the latest
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.RevisionId = 90
+	old, err := client.GetWordWithOptions("green", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from Client.GetWordWithOptions: %q`, err)
+	}
+	if old.Meaning != "Of a green colour." || old.Meaning == lw.Meaning {
+		t.Fatalf(`Client.GetWordWithOptions: expected the meaning from revision 90, got %q`, old.Meaning)
+	}
+	if old.Revision == nil || old.Revision.RevisionId != 90 {
+		t.Fatalf(`old.Revision: expected revision 90, got %v`, old.Revision)
+	}
+
+	// both revisions, and what they were, come from the cache the second time round
+	count := len(*requests)
+	lw, _ = client.GetWord("green", "en")
+	old, _ = client.GetWordWithOptions("green", "en", options)
+	if len(*requests) != count {
+		t.Fatalf(`Client: expected no more requests once cached, got %v`, len(*requests)-count)
+	}
+	if lw.Revision == nil || lw.Revision.RevisionId != 100 || old.Revision == nil || old.Revision.RevisionId != 90 {
+		t.Fatalf(`Client: expected revisions 100 and 90 from the cache, got %v and %v`, lw.Revision, old.Revision)
+	}
+
+	// a revision which doesn't exist is an API error
+	options.RevisionId = 12345
+	_, err = client.GetWordWithOptions("green", "en", options)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "nosuchrevid" {
+		t.Fatalf(`Client.GetWordWithOptions: expected an APIError with code "nosuchrevid", got %q`, err)
+	}
+
+	// nor is one of another page
+	options.RevisionId = 80
+	_, err = client.GetWordWithOptions("green", "en", options)
+	var mismatchErr *RevisionMismatchError
+	if !errors.Is(err, ErrRevisionMismatch) || !errors.As(err, &mismatchErr) || mismatchErr.PageTitle != "blue" {
+		t.Fatalf(`Client.GetWordWithOptions: expected a RevisionMismatchError for page "blue", got %q`, err)
+	}
+
+	// and sources with only one version of each page can't be pinned
+	options.Source = MapSource{"green": testWikitext}
+	options.RevisionId = 90
+	if _, err := (&Client{}).GetWordWithOptions("green", "en", options); err == nil {
+		t.Fatalf(`Client.GetWordWithOptions: expected an error pinning a MapSource to a revision`)
+	}
+}
+
+func TestClientAllLanguages(t *testing.T) {
+	server, requests := newTestApi(t, map[string]string{"green": testWikitext})
+	dir := t.TempDir()
+	client := &Client{BaseUrl: server.URL, OutputDir: dir}
+
+	words, err := client.GetAllLanguages("green")
+	if err != nil {
+		t.Fatalf(`Error from Client.GetAllLanguages: %q`, err)
+	}
+	if len(words) != 2 || words["en"].Meaning != "Having green as its color." || words["nl"].Meaning != "A Dutch word." {
+		t.Fatalf(`Client.GetAllLanguages: expected English and Dutch, got %+v`, words)
+	}
+	if words["nl"].LanguageName != "Dutch" || words["nl"].Word != "green" || words["nl"].Revision == nil {
+		t.Fatalf(`Client.GetAllLanguages: expected the Dutch word with its revision, got %+v`, words["nl"])
+	}
+
+	// the page is only fetched once, however many languages it has
+	fetches := 0
+	for _, r := range *requests {
+		if r.Form.Get("titles") != "" {
+			fetches++
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf(`Client.GetAllLanguages: expected the page to be fetched once, got %d`, fetches)
+	}
+	for _, fileName := range []string{"en-green.json", "nl-green.json"} {
+		if _, err := os.Stat(filepath.Join(dir, fileName)); err != nil {
+			t.Fatalf(`Client.GetAllLanguages: expected %v to be written: %q`, fileName, err)
+		}
+	}
+
+	// the languages can be limited, e.g. to IndoEuropean or to a single language
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_Parts | Sec_Meanings
+	options.PageLanguages = []string{"nl"}
+	words, err = client.GetAllLanguagesWithOptions("green", options)
+	if err != nil {
+		t.Fatalf(`Error from Client.GetAllLanguagesWithOptions: %q`, err)
+	}
+	if _, ok := words["en"]; len(words) != 1 || ok {
+		t.Fatalf(`Client.GetAllLanguagesWithOptions: expected only Dutch, got %+v`, words)
+	}
+
+	if _, err := client.GetAllLanguages("blue"); !errors.Is(err, ErrPageNotFound) {
+		t.Fatalf(`Client.GetAllLanguages: expected ErrPageNotFound for a missing page, got %q`, err)
+	}
+}
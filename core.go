@@ -1,13 +1,22 @@
 package wiktionary
 
 import (
-	"errors"
+	"context"
 	"os"
+	"path/filepath"
 )
 
 type WiktionaryOptions struct {
 	RequiredSections  int16
 	RequiredLanguages []string
+	Source            PageSource // where to read pages from - defaults to the client's API
+	BatchRendering    bool       // render all of a page's templates in one API call, rather than one call per line
+	Cache             *DiskCache // cache pages and rendered text on disk - defaults to the client's cache
+	RevisionId        int64      // parse this revision of the page (its oldid) rather than the latest
+	PageLanguages     []string   // for GetAllLanguages, the languages to parse - every language on the page if empty
+
+	batch     *renderBatch // the batch of rendered text for the current page
+	outputDir string       // where to write the debug files for each word, if anywhere
 }
 
 const (
@@ -24,70 +33,82 @@ const (
 	Sec_Antonyms               int16 = 0x0400
 	Sec_Anagrams               int16 = 0x0800
 	Sec_Alternatives           int16 = 0x1000
+	Sec_Other_Sections         int16 = 0x2000 // sections with no parser of their own, e.g. Usage notes, kept as they are
+	Sec_Relations              int16 = 0x4000 // the terms in Synonyms, Derived terms, Hyponyms and the other relation sections
 )
 const Sec_Core = Sec_Etymology_Text | Sec_Etymology_Words | Sec_IPA | Sec_Parts | Sec_Meanings
-const Sec_All = 0x0FFF
+const Sec_All = 0x0FFF | Sec_Other_Sections | Sec_Relations
 
 func GetWord(word string, langCode string) (LanguageWord, error) {
-	var options WiktionaryOptions
-	options.RequiredSections = Sec_All
-	options.RequiredLanguages = AllLanguages
-	lw, err := processWord(word, langCode, options)
-	return lw, err
+	return DefaultClient.GetWord(word, langCode)
+}
+
+func GetWordContext(ctx context.Context, word string, langCode string) (LanguageWord, error) {
+	return DefaultClient.GetWordContext(ctx, word, langCode)
 }
 
 func GetWordWithOptions(word string, langCode string, options WiktionaryOptions) (LanguageWord, error) {
-	lw, err := processWord(word, langCode, options)
-	return lw, err
+	return DefaultClient.GetWordWithOptions(word, langCode, options)
+}
+
+func GetWordWithOptionsContext(ctx context.Context, word string, langCode string, options WiktionaryOptions) (LanguageWord, error) {
+	return DefaultClient.GetWordWithOptionsContext(ctx, word, langCode, options)
+}
+
+func GetAllLanguages(word string) (map[string]LanguageWord, error) {
+	return DefaultClient.GetAllLanguages(word)
+}
+
+func GetAllLanguagesContext(ctx context.Context, word string) (map[string]LanguageWord, error) {
+	return DefaultClient.GetAllLanguagesContext(ctx, word)
+}
+
+func GetAllLanguagesWithOptions(word string, options WiktionaryOptions) (map[string]LanguageWord, error) {
+	return DefaultClient.GetAllLanguagesWithOptions(word, options)
+}
+
+func GetAllLanguagesWithOptionsContext(ctx context.Context, word string, options WiktionaryOptions) (map[string]LanguageWord, error) {
+	return DefaultClient.GetAllLanguagesWithOptionsContext(ctx, word, options)
 }
 
 func GetMeaning(word string, langCode string) (string, error) {
-	var options WiktionaryOptions
-	options.RequiredSections = Sec_Parts | Sec_Meanings
-	options.RequiredLanguages = AllLanguages
-	lw, err := processWord(word, langCode, options)
-	return lw.Meaning, err
+	return DefaultClient.GetMeaning(word, langCode)
+}
+
+func GetMeaningContext(ctx context.Context, word string, langCode string) (string, error) {
+	return DefaultClient.GetMeaningContext(ctx, word, langCode)
 }
 
 func GetTranslations(word string, langCode string, requiredLanguages []string) ([]TranslatedWord, error) {
-	var options WiktionaryOptions
-	options.RequiredSections = Sec_Parts | Sec_Translations
-	options.RequiredLanguages = requiredLanguages
-	lw, err := processWord(word, langCode, options)
-	if err != nil {
-		return nil, err
-	}
-	var tr []TranslatedWord
-	// iterate across all etymologies and parts to find the translations
-	for _, etym := range lw.Etymologies {
-		for _, part := range etym.Parts {
-			tr = append(tr, part.Translations...)
-		}
-	}
-	return tr, err
+	return DefaultClient.GetTranslations(word, langCode, requiredLanguages)
+}
+
+func GetTranslationsContext(ctx context.Context, word string, langCode string, requiredLanguages []string) ([]TranslatedWord, error) {
+	return DefaultClient.GetTranslationsContext(ctx, word, langCode, requiredLanguages)
 }
 
 func GetIpaPronunciation(word string, langCode string) (string, error) {
-	var options WiktionaryOptions
-	options.RequiredSections = Sec_IPA
-	options.RequiredLanguages = AllLanguages
-	lw, err := processWord(word, langCode, options)
-	if err != nil {
-		return "", err
-	}
-	if lw.Ipa == "" {
-		return "", errors.New("no IPA found")
-	}
-	return lw.Ipa, nil
+	return DefaultClient.GetIpaPronunciation(word, langCode)
+}
+
+func GetIpaPronunciationContext(ctx context.Context, word string, langCode string) (string, error) {
+	return DefaultClient.GetIpaPronunciationContext(ctx, word, langCode)
 }
 
 func GetEtymologyTree(word string, langCode string, languages []string) TreeNode {
-	// get a full etymology tree containing ancestor words
-	etym, err := getEtymologyTree(word, langCode, languages)
-	if err != nil {
-		return etym
-	}
-	return etym // TODO
+	return DefaultClient.GetEtymologyTree(word, langCode, languages)
+}
+
+func GetEtymologyTreeContext(ctx context.Context, word string, langCode string, languages []string) (TreeNode, error) {
+	return DefaultClient.GetEtymologyTreeContext(ctx, word, langCode, languages)
+}
+
+func DownloadAudio(audio Audio, dir string) (string, error) {
+	return DefaultClient.DownloadAudio(audio, dir)
+}
+
+func DownloadAudioContext(ctx context.Context, audio Audio, dir string) (string, error) {
+	return DefaultClient.DownloadAudioContext(ctx, audio, dir)
 }
 
 func GetLanguageFromCode(code string) string {
@@ -95,40 +116,156 @@ func GetLanguageFromCode(code string) string {
 	return getLanguageFromCode(code)
 }
 
-func processWord(word string, langCode string, options WiktionaryOptions) (LanguageWord, error) {
+func GetCodeFromLanguage(name string) string {
+	// convert a language name to its code, e.g. for "English" or "english" return "en" - alternative
+	// names such as "Farsi" are accepted too
+	return getCodeFromLanguage(name)
+}
+
+func ResolveLanguage(codeOrName string) (string, error) {
+	// accept a language code or name, e.g. "la" or "Latin", and return the code - an unknown
+	// language returns an *UnknownLanguageCodeError with the closest matches as Suggestions
+	return resolveLanguage(codeOrName)
+}
+
+func processWord(ctx context.Context, word string, langCode string, options WiktionaryOptions) (LanguageWord, error) {
 	nilWord := new(LanguageWord)
-	// get the JSON content for the requested word
-	wordData, err := getWordDataFromWiktionary(word, langCode)
+	if err := checkCanceled(ctx, word, langCode); err != nil {
+		return *nilWord, err
+	}
+	// the language can be given by its code or its name, e.g. "la" or "Latin"
+	langCode, err := resolveLanguage(langCode)
 	if err != nil {
 		return *nilWord, err
 	}
+	// and so can the languages required, e.g. of translations
+	requiredLanguages, err := resolveLanguages(options.RequiredLanguages)
+	if err != nil {
+		return *nilWord, err
+	}
+	options.RequiredLanguages = requiredLanguages
 
-	// extract the wikitext from the JSON content
-	wikitext, err := getWikitext(wordData, word)
+	// get the wikitext for the requested word from the page source
+	page, err := getPageWikitext(ctx, word, langCode, options)
 	if err != nil {
+		if errc := checkCanceled(ctx, word, langCode); errc != nil {
+			return *nilWord, errc
+		}
 		return *nilWord, err
 	}
 
 	// process the wikitext into sections
-	sections := processWikitext(wikitext)
+	sections := processWikitext(page.Wikitext)
 
-	// get the relevant sections for the language
-	languageSections, err := extractLanguageSections(word, langCode, sections)
+	// get the relevant sections for the language - if the page was a redirect, these are
+	// for the word it redirected to
+	resolvedWord := getWordFromTitle(page.Title)
+	languageSections, err := extractLanguageSections(resolvedWord, langCode, sections)
 	if err != nil {
 		return *nilWord, err
 	}
 
 	// parse the language sections and build a Language struct
-	lw := parseSections(word, langCode, languageSections, options)
+	// the entry keeps the word as requested, but records the page it came from
+	lw := parseSections(ctx, resolvedWord, langCode, languageSections, options)
+	lw.Word = word
+	lw.Title = page.Title
+	lw.Revision = page.revision()
+
+	// if the context was cancelled while parsing, some of the text won't have been rendered
+	if err := checkCanceled(ctx, word, langCode); err != nil {
+		return *nilWord, err
+	}
 
 	// for debug purposes, write the word data to a JSON file and a wikitext file
 	// TODO - remove these once we are done
-	errw := writeJson(word, langCode, &lw)
-	if errw != nil {
-		return lw, errw
+	if options.outputDir != "" {
+		errw := writeJson(options.outputDir, word, langCode, &lw)
+		if errw != nil {
+			return lw, errw
+		}
+		fileName := filepath.Join(options.outputDir, langCode+"-"+word+".wikitext")
+		os.WriteFile(fileName, []byte(page.Wikitext), 0666)
 	}
-	fileName := langCode + "-" + word + ".wikitext"
-	os.WriteFile(fileName, []byte(wikitext), 0666)
 
 	return lw, nil
 }
+
+func processAllLanguages(ctx context.Context, word string, options WiktionaryOptions) (map[string]LanguageWord, error) {
+	// parse every language on the word's page, fetching the page only once
+	if err := checkCanceled(ctx, word, ""); err != nil {
+		return nil, err
+	}
+
+	// the languages can be given by their codes or their names
+	pageLanguages, err := resolveLanguages(options.PageLanguages)
+	if err != nil {
+		return nil, err
+	}
+	options.PageLanguages = pageLanguages
+	requiredLanguages, err := resolveLanguages(options.RequiredLanguages)
+	if err != nil {
+		return nil, err
+	}
+	options.RequiredLanguages = requiredLanguages
+
+	// get the wikitext for the word - with no language, the word is the page title as it stands
+	page, err := getPageWikitext(ctx, word, "", options)
+	if err != nil {
+		if errc := checkCanceled(ctx, word, ""); errc != nil {
+			return nil, errc
+		}
+		return nil, err
+	}
+
+	// process the wikitext into sections, then parse each language-level section in turn
+	sections := processWikitext(page.Wikitext)
+	resolvedWord := getWordFromTitle(page.Title)
+	words := make(map[string]LanguageWord)
+	for _, langCode := range getPageLanguages(sections) {
+		if !pageLanguageRequired(options, langCode) {
+			continue
+		}
+		languageSections, err := extractLanguageSections(resolvedWord, langCode, sections)
+		if err != nil {
+			continue
+		}
+		lw := parseSections(ctx, resolvedWord, langCode, languageSections, options)
+		lw.Word = word
+		lw.Title = page.Title
+		lw.Revision = page.revision()
+		words[langCode] = lw
+	}
+
+	// if the context was cancelled while parsing, some of the text won't have been rendered
+	if err := checkCanceled(ctx, word, ""); err != nil {
+		return nil, err
+	}
+
+	// for debug purposes, write the files for each language as processWord does
+	if options.outputDir != "" {
+		for langCode, lw := range words {
+			lw := lw
+			if errw := writeJson(options.outputDir, word, langCode, &lw); errw != nil {
+				return words, errw
+			}
+			fileName := filepath.Join(options.outputDir, langCode+"-"+word+".wikitext")
+			os.WriteFile(fileName, []byte(page.Wikitext), 0666)
+		}
+	}
+
+	return words, nil
+}
+
+func pageLanguageRequired(options WiktionaryOptions, langCode string) bool {
+	// with no PageLanguages (or AllLanguages), every language on the page is required
+	if len(options.PageLanguages) == 0 || options.PageLanguages[0] == "all" {
+		return true
+	}
+	for _, val := range options.PageLanguages {
+		if langCode == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,330 @@
+package wiktionary
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// kinds of cache entry
+const (
+	Cache_Page = "page" // the wikitext of a page
+	Cache_Text = "text" // the HTML rendered for a piece of wikitext on a page
+)
+
+// DiskCache stores page wikitext and rendered text on disk, so that repeated lookups don't need
+// to call Wiktionary again - set it in WiktionaryOptions.Cache, or in a Client to use it for
+// every lookup the client makes
+type DiskCache struct {
+	Dir     string
+	TTL     time.Duration // how long entries stay valid - 0 means they never expire
+	MaxSize int64         // the most bytes of data to hold - 0 means no limit
+
+	mu    sync.Mutex
+	size  int64 // running total of the data held, once sized is set
+	sized bool
+}
+
+type CacheEntry struct {
+	Kind     string    `json:"kind"`
+	Title    string    `json:"title"`
+	Revision int64     `json:"rev,omitempty"`  // for pages, 0 if this is the latest revision
+	Text     string    `json:"text,omitempty"` // for rendered text, the wikitext which was rendered
+	Stored   time.Time `json:"stored"`
+	LastUsed time.Time `json:"-"`
+	Size     int64     `json:"size"`
+}
+
+type cacheFile struct {
+	CacheEntry
+	Data          string    `json:"data"`
+	ResolvedTitle string    `json:"resolved-title,omitempty"` // for pages, the title reached after any redirects
+	PageRevision  *Revision `json:"page-revision,omitempty"`  // for pages, the revision the wikitext came from
+}
+
+func pageCacheKey(title string, revision int64) string {
+	return Cache_Page + "\x00" + title + "\x00" + strconv.FormatInt(revision, 10)
+}
+
+func textCacheKey(text string, title string) string {
+	return Cache_Text + "\x00" + title + "\x00" + text
+}
+
+func (c *DiskCache) fileName(key string) string {
+	// keys can be any text, so name the files by a hash of the key
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *DiskCache) GetPage(title string, revision int64) (string, bool) {
+	file, ok := c.get(pageCacheKey(title, revision))
+	return file.Data, ok
+}
+
+func (c *DiskCache) getPageData(title string, revision int64) (*PageResponse, bool) {
+	// return the page as it was stored by putPageData, with its resolved title and revision
+	file, ok := c.get(pageCacheKey(title, revision))
+	if !ok {
+		return nil, false
+	}
+	page := &PageResponse{Title: file.ResolvedTitle, Wikitext: file.Data}
+	if page.Title == "" {
+		page.Title = title
+	}
+	if file.PageRevision != nil {
+		page.PageId = file.PageRevision.PageId
+		page.RevId = file.PageRevision.RevisionId
+		page.Timestamp = file.PageRevision.Timestamp
+	}
+	return page, true
+}
+
+func (c *DiskCache) GetText(text string, title string) (string, bool) {
+	file, ok := c.get(textCacheKey(text, title))
+	return file.Data, ok
+}
+
+func (c *DiskCache) PutPage(title string, revision int64, wikitext string) error {
+	return c.putPageData(title, revision, &PageResponse{Title: title, Wikitext: wikitext})
+}
+
+func (c *DiskCache) putPageData(title string, revision int64, page *PageResponse) error {
+	// store a page under the title requested, along with the title and revision it resolved to
+	entry := CacheEntry{Kind: Cache_Page, Title: title, Revision: revision}
+	file := cacheFile{CacheEntry: entry, Data: page.Wikitext, PageRevision: page.revision()}
+	if page.Title != title {
+		file.ResolvedTitle = page.Title
+	}
+	return c.put(pageCacheKey(title, revision), file)
+}
+
+func (c *DiskCache) PutText(text string, title string, html string) error {
+	entry := CacheEntry{Kind: Cache_Text, Title: title, Text: text}
+	return c.put(textCacheKey(text, title), cacheFile{CacheEntry: entry, Data: html})
+}
+
+func (c *DiskCache) get(key string) (cacheFile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fileName := c.fileName(key)
+	file, err := readCacheFile(fileName)
+	if err != nil {
+		return cacheFile{}, false
+	}
+	if c.expired(file.CacheEntry) {
+		os.Remove(fileName)
+		return cacheFile{}, false
+	}
+
+	// record the use, so that the least recently used entries are evicted first
+	now := time.Now()
+	os.Chtimes(fileName, now, now)
+	return file, true
+}
+
+func (c *DiskCache) put(key string, file cacheFile) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.Dir, 0777); err != nil {
+		return err
+	}
+	file.Stored = time.Now()
+	file.Size = int64(len(file.Data))
+	b, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.fileName(key), b, 0666); err != nil {
+		return err
+	}
+	return c.evict(file.Size)
+}
+
+func (c *DiskCache) expired(entry CacheEntry) bool {
+	return c.TTL > 0 && time.Since(entry.Stored) > c.TTL
+}
+
+func (c *DiskCache) evict(added int64) error {
+	// keep a running total of the size, so we only need to read the entries when over the limit
+	if c.MaxSize <= 0 {
+		return nil
+	}
+	if c.sized {
+		c.size += added
+		if c.size <= c.MaxSize {
+			return nil
+		}
+	}
+
+	// remove expired entries, then the least recently used until we are within the size limit
+	entries, err := c.readEntries()
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+	var total int64
+	var live []cacheListing
+	for _, e := range entries {
+		if c.expired(e.CacheEntry) {
+			os.Remove(e.fileName)
+			continue
+		}
+		total += e.Size
+		live = append(live, e)
+	}
+	for i := 0; total > c.MaxSize && i < len(live); i++ {
+		os.Remove(live[i].fileName)
+		total -= live[i].Size
+	}
+	c.size = total
+	c.sized = true
+	return nil
+}
+
+type cacheListing struct {
+	CacheEntry
+	fileName string
+}
+
+func (c *DiskCache) readEntries() ([]cacheListing, error) {
+	files, err := os.ReadDir(c.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []cacheListing
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		fileName := filepath.Join(c.Dir, f.Name())
+		file, err := readCacheFile(fileName)
+		if err != nil {
+			continue // not one of ours, or removed since we listed the directory
+		}
+		if info, err := f.Info(); err == nil {
+			file.LastUsed = info.ModTime()
+		}
+		entries = append(entries, cacheListing{CacheEntry: file.CacheEntry, fileName: fileName})
+	}
+	return entries, nil
+}
+
+func readCacheFile(fileName string) (cacheFile, error) {
+	var file cacheFile
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return file, err
+	}
+	err = json.Unmarshal(b, &file)
+	return file, err
+}
+
+func (c *DiskCache) Entries() ([]CacheEntry, error) {
+	// list the entries currently in the cache, including any which have expired
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	listings, err := c.readEntries()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]CacheEntry, len(listings))
+	for i, l := range listings {
+		entries[i] = l.CacheEntry
+	}
+	return entries, nil
+}
+
+func (c *DiskCache) Size() (int64, error) {
+	// the total bytes of data held in the cache
+	entries, err := c.Entries()
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	return total, err
+}
+
+func (c *DiskCache) InvalidatePage(title string) error {
+	// remove every entry for the page - all of its revisions and all of its rendered text
+	return c.invalidate(func(e CacheEntry) bool { return e.Title == title })
+}
+
+func (c *DiskCache) InvalidateText(text string, title string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sized = false
+	err := os.Remove(c.fileName(textCacheKey(text, title)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (c *DiskCache) InvalidateExpired() error {
+	return c.invalidate(c.expired)
+}
+
+func (c *DiskCache) Clear() error {
+	return c.invalidate(func(e CacheEntry) bool { return true })
+}
+
+func (c *DiskCache) invalidate(match func(CacheEntry) bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sized = false
+	entries, err := c.readEntries()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if match(e.CacheEntry) {
+			if err := os.Remove(e.fileName); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *DiskCache) Prewarm(words []string, langCode string, options WiktionaryOptions) error {
+	return c.PrewarmContext(context.Background(), words, langCode, options)
+}
+
+func (c *DiskCache) PrewarmContext(ctx context.Context, words []string, langCode string, options WiktionaryOptions) error {
+	// fetch and parse each word so that its page and rendered text are cached
+	// this follows processWord, but without writing any output files
+	options.Cache = c
+	for _, word := range words {
+		if err := checkCanceled(ctx, word, langCode); err != nil {
+			return err
+		}
+		page, err := getPageWikitext(ctx, word, langCode, options)
+		if err != nil {
+			return err
+		}
+		sections := processWikitext(page.Wikitext)
+		resolvedWord := getWordFromTitle(page.Title)
+		languageSections, err := extractLanguageSections(resolvedWord, langCode, sections)
+		if err != nil {
+			return err
+		}
+		parseSections(ctx, resolvedWord, langCode, languageSections, options)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package wiktionary
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDiskCache(t *testing.T) {
+	cache := &DiskCache{Dir: t.TempDir()}
+
+	if _, ok := cache.GetPage("red", 0); ok {
+		t.Fatalf(`DiskCache.GetPage: expected no entry in an empty cache`)
+	}
+	cache.PutPage("red", 0, "==English==")
+	cache.PutText("{{m|en|red}}", "red", "<p>red</p>")
+	cache.PutText("{{m|en|red}}", "green", "<p>green</p>")
+
+	if wikitext, ok := cache.GetPage("red", 0); !ok || wikitext != "==English==" {
+		t.Fatalf(`DiskCache.GetPage: expected %q, got %q`, "==English==", wikitext)
+	}
+	if _, ok := cache.GetPage("red", 1234); ok {
+		t.Fatalf(`DiskCache.GetPage: expected no entry for a different revision`)
+	}
+	// the same text is cached separately for each page
+	if html, ok := cache.GetText("{{m|en|red}}", "green"); !ok || html != "<p>green</p>" {
+		t.Fatalf(`DiskCache.GetText: expected %q, got %q`, "<p>green</p>", html)
+	}
+
+	entries, err := cache.Entries()
+	if err != nil || len(entries) != 3 {
+		t.Fatalf(`DiskCache.Entries: expected 3 entries, got %v (%v)`, len(entries), err)
+	}
+
+	// invalidating a page removes its wikitext and its rendered text
+	cache.InvalidatePage("red")
+	entries, _ = cache.Entries()
+	if len(entries) != 1 || entries[0].Title != "green" {
+		t.Fatalf(`DiskCache.InvalidatePage: expected only the entry for green, got %v`, entries)
+	}
+	cache.Clear()
+	entries, _ = cache.Entries()
+	if len(entries) != 0 {
+		t.Fatalf(`DiskCache.Clear: expected no entries, got %v`, entries)
+	}
+}
+
+func TestDiskCacheExpiry(t *testing.T) {
+	cache := &DiskCache{Dir: t.TempDir(), TTL: time.Hour}
+	cache.PutPage("red", 0, "==English==")
+	cache.PutPage("green", 0, "==English==")
+
+	// backdate one of the entries
+	fileName := cache.fileName(pageCacheKey("red", 0))
+	file, _ := readCacheFile(fileName)
+	file.Stored = time.Now().Add(-2 * time.Hour)
+	b, _ := json.Marshal(file)
+	os.WriteFile(fileName, b, 0666)
+
+	if _, ok := cache.GetPage("red", 0); ok {
+		t.Fatalf(`DiskCache.GetPage: expected an expired entry to be missing`)
+	}
+	if _, ok := cache.GetPage("green", 0); !ok {
+		t.Fatalf(`DiskCache.GetPage: expected an unexpired entry to be found`)
+	}
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	cache := &DiskCache{Dir: t.TempDir(), MaxSize: 25}
+	cache.PutPage("one", 0, "1234567890")
+	cache.PutPage("two", 0, "1234567890")
+
+	// use the first entry, so the second is the least recently used
+	past := time.Now().Add(-time.Minute)
+	os.Chtimes(cache.fileName(pageCacheKey("two", 0)), past, past)
+	cache.GetPage("one", 0)
+
+	cache.PutPage("three", 0, "1234567890")
+	if _, ok := cache.GetPage("two", 0); ok {
+		t.Fatalf(`DiskCache: expected the least recently used entry to be evicted`)
+	}
+	if _, ok := cache.GetPage("one", 0); !ok {
+		t.Fatalf(`DiskCache: expected the recently used entry to be kept`)
+	}
+	if size, _ := cache.Size(); size != 20 {
+		t.Fatalf(`DiskCache.Size: expected 20, got %v`, size)
+	}
+}
+
+func TestDiskCacheLookups(t *testing.T) {
+	chdirTemp(t)
+	cache := &DiskCache{Dir: t.TempDir()}
+	renderer := &testRenderer{MapSource: MapSource{"green": testBatchWikitext}}
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.RequiredLanguages = AllLanguages
+	options.Source = renderer
+	options.Cache = cache
+
+	// prewarming renders everything once, so a lookup needs no more calls
+	if err := cache.Prewarm([]string{"green"}, "en", options); err != nil {
+		t.Fatalf(`Error from DiskCache.Prewarm: %q`, err)
+	}
+	calls := renderer.calls
+	if calls == 0 {
+		t.Fatalf(`DiskCache.Prewarm: expected some text to be rendered`)
+	}
+	if _, ok := cache.GetPage("green", 0); !ok {
+		t.Fatalf(`DiskCache.Prewarm: expected the page to be cached`)
+	}
+
+	// the page is served from the cache, even once it has gone from the source
+	delete(renderer.MapSource, "green")
+	lw, err := GetWordWithOptions("green", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	if renderer.calls != calls {
+		t.Fatalf(`GetWordWithOptions: expected no more rendering calls, got %v`, renderer.calls-calls)
+	}
+	if lw.Etymologies[0].Parts[0].Headword != "EN-ADJ" {
+		t.Fatalf(`GetWordWithOptions: expected headword %q, got %q`, "EN-ADJ", lw.Etymologies[0].Parts[0].Headword)
+	}
+}
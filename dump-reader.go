@@ -0,0 +1,172 @@
+package wiktionary
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// namespaces of interest in a Wiktionary dump
+const (
+	Ns_Main           = 0
+	Ns_Reconstruction = 118
+)
+
+type DumpOptions struct {
+	Namespaces []int    // defaults to the main and Reconstruction namespaces
+	Languages  []string // language codes to extract - defaults to every language on each page
+	Options    WiktionaryOptions
+}
+
+// a single <page> element from a pages-articles dump - we only read the parts we need
+type dumpPage struct {
+	Title    string `xml:"title"`
+	Ns       int    `xml:"ns"`
+	Id       int64  `xml:"id"`
+	Redirect *struct {
+		Title string `xml:"title,attr"`
+	} `xml:"redirect"`
+	RevisionId int64     `xml:"revision>id"`
+	Timestamp  time.Time `xml:"revision>timestamp"`
+	Text       string    `xml:"revision>text"`
+}
+
+func ReadDump(path string, dumpOptions DumpOptions, callback func(LanguageWord) error) error {
+	return ReadDumpContext(context.Background(), path, dumpOptions, callback)
+}
+
+func ReadDumpContext(ctx context.Context, path string, dumpOptions DumpOptions, callback func(LanguageWord) error) error {
+	// read a Wiktionary pages-articles dump file, which may be plain XML or bzip2 compressed
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ReadDumpFromContext(ctx, f, dumpOptions, callback)
+}
+
+func ReadDumpFrom(r io.Reader, dumpOptions DumpOptions, callback func(LanguageWord) error) error {
+	return ReadDumpFromContext(context.Background(), r, dumpOptions, callback)
+}
+
+func ReadDumpFromContext(ctx context.Context, r io.Reader, dumpOptions DumpOptions, callback func(LanguageWord) error) error {
+	// check the required languages before reading what could be a very large file - they can
+	// be given by their codes or their names
+	languages, err := resolveLanguages(dumpOptions.Languages)
+	if err != nil {
+		return err
+	}
+	dumpOptions.Languages = languages
+
+	// detect bzip2 compression from the magic number at the start of the stream
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(3)
+	var input io.Reader = br
+	if string(magic) == "BZh" {
+		input = bzip2.NewReader(br)
+	}
+
+	namespaces := dumpOptions.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []int{Ns_Main, Ns_Reconstruction}
+	}
+
+	// stream the XML one token at a time, decoding each page as we reach it
+	// so only a single page is held in memory at once
+	decoder := xml.NewDecoder(input)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "page" {
+			continue
+		}
+
+		var page dumpPage
+		if err := decoder.DecodeElement(&page, &start); err != nil {
+			return err
+		}
+		if page.Redirect != nil || !namespaceRequired(namespaces, page.Ns) {
+			continue
+		}
+		if err := checkCanceled(ctx, "", ""); err != nil {
+			return err
+		}
+		if err := processDumpPage(ctx, page, dumpOptions, callback); err != nil {
+			return err
+		}
+	}
+}
+
+func processDumpPage(ctx context.Context, page dumpPage, dumpOptions DumpOptions, callback func(LanguageWord) error) error {
+	// work out the word, and for reconstructions the only language it can be in
+	word := page.Title
+	reconLang := ""
+	if page.Ns == Ns_Reconstruction {
+		parts := strings.SplitN(strings.TrimPrefix(page.Title, "Reconstruction:"), "/", 2)
+		if len(parts) != 2 {
+			return nil
+		}
+		reconLang = getCodeFromLanguage(parts[0])
+		word = "*" + parts[1]
+	}
+
+	// process the wikitext into sections
+	sections := processWikitext(page.Text)
+
+	// template rendering uses the page source, so unless one has been given, use the page itself
+	// this avoids an API call for every line of every page
+	options := dumpOptions.Options
+	if options.Source == nil {
+		options.Source = MapSource{page.Title: page.Text}
+	}
+
+	for _, langCode := range getDumpPageLanguages(sections, dumpOptions.Languages) {
+		if reconLang != "" && langCode != reconLang {
+			continue
+		}
+
+		// get the relevant sections for the language - if it's not on the page, move on
+		languageSections, err := extractLanguageSections(word, langCode, sections)
+		if err != nil {
+			continue
+		}
+
+		lw := parseSections(ctx, word, langCode, languageSections, options)
+		lw.Title = page.Title
+		lw.Revision = &Revision{PageId: page.Id, RevisionId: page.RevisionId, Timestamp: page.Timestamp}
+		if err := callback(lw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getDumpPageLanguages(sections []Section, languages []string) []string {
+	// if specific languages are required, just use those
+	if len(languages) > 0 && languages[0] != "all" {
+		return languages
+	}
+
+	// otherwise find every language on the page
+	return getPageLanguages(sections)
+}
+
+func namespaceRequired(namespaces []int, ns int) bool {
+	for _, val := range namespaces {
+		if ns == val {
+			return true
+		}
+	}
+	return false
+}
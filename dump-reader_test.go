@@ -0,0 +1,70 @@
+package wiktionary
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadDump(t *testing.T) {
+	for _, path := range []string{"testdata/dump.xml", "testdata/dump.xml.bz2"} {
+		var words []LanguageWord
+		var options DumpOptions
+		options.Options.RequiredSections = Sec_All
+		options.Options.RequiredLanguages = AllLanguages
+		err := ReadDump(path, options, func(lw LanguageWord) error {
+			words = append(words, lw)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf(`Error from ReadDump(%q): %q`, path, err)
+		}
+
+		// redirects and other namespaces are skipped
+		if len(words) != 3 {
+			t.Fatalf(`ReadDump(%q): expected 3 words, got %v`, path, len(words))
+		}
+		expectedWords := []string{"en green", "nl green", "gem-pro *grōnijaz"}
+		for i, lw := range words {
+			if lw.LanguageCode+" "+lw.Word != expectedWords[i] {
+				t.Fatalf(`ReadDump(%q) word %v: expected %q, got %q`, path, i, expectedWords[i], lw.LanguageCode+" "+lw.Word)
+			}
+		}
+		if words[0].Meaning != "Having green as its color." {
+			t.Fatalf(`ReadDump(%q): expected meaning %q, got %q`, path, "Having green as its color.", words[0].Meaning)
+		}
+		expected := Revision{PageId: 1, RevisionId: 10, Timestamp: time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)}
+		if words[0].Revision == nil || *words[0].Revision != expected {
+			t.Fatalf(`ReadDump(%q): expected revision %v, got %v`, path, expected, words[0].Revision)
+		}
+	}
+}
+
+func TestReadDumpFiltered(t *testing.T) {
+	var words []LanguageWord
+	var options DumpOptions
+	options.Namespaces = []int{Ns_Main}
+	options.Languages = []string{"nl", "de"}
+	options.Options.RequiredSections = Sec_Core
+	err := ReadDump("testdata/dump.xml", options, func(lw LanguageWord) error {
+		words = append(words, lw)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf(`Error from ReadDump: %q`, err)
+	}
+	if len(words) != 1 || words[0].LanguageCode != "nl" {
+		t.Fatalf(`ReadDump: expected only the Dutch word, got %v`, words)
+	}
+
+	// an error from the callback stops the read
+	stop := errors.New("stop")
+	count := 0
+	err = ReadDump("testdata/dump.xml", DumpOptions{}, func(lw LanguageWord) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Fatalf(`ReadDump: expected to stop after 1 word, got %v words and error %q`, count, err)
+	}
+}
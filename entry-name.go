@@ -0,0 +1,101 @@
+package wiktionary
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// combining marks used in the entry-name rules
+const (
+	mark_grave        = "\u0300"
+	mark_acute        = "\u0301"
+	mark_tilde        = "\u0303"
+	mark_macron       = "\u0304"
+	mark_breve        = "\u0306"
+	mark_dot_above    = "\u0307"
+	mark_diaeresis    = "\u0308"
+	mark_double_grave = "\u030f"
+	mark_inv_breve    = "\u0311"
+)
+
+// an entryNameRule turns a word as written in a language's text (e.g. with vowel lengths or
+// stress marked) into the title of its page, modelled on the entry_name rules in Wiktionary's
+// language data - marks are removed from the decomposed (NFD) text, then replace is applied
+type entryNameRule struct {
+	marks   string            // combining marks to remove
+	bases   string            // if set, only remove the marks from these letters
+	replace *strings.Replacer // any other characters to change, after the marks are removed
+}
+
+// the combining acute and grave, as used to mark stress in the East Slavic languages
+var eastSlavicRule = entryNameRule{marks: mark_acute + mark_grave}
+
+var entryNameRules = map[string]entryNameRule{
+	// Latin marks vowel length with macrons and breves, and sometimes a diaeresis for hiatus
+	"la": {marks: mark_macron + mark_breve + mark_diaeresis},
+	// Old English marks long vowels, and palatal ċ and ġ
+	"ang": {marks: mark_macron + mark_dot_above},
+	// Ancient Greek marks vowel length on α, ι and υ, but keeps its accents and breathings
+	"grc": {marks: mark_macron + mark_breve},
+	"ru":  eastSlavicRule,
+	"uk":  eastSlavicRule,
+	"be":  eastSlavicRule,
+	"rue": eastSlavicRule,
+	"orv": eastSlavicRule,
+	// Serbo-Croatian marks tone and length on vowels (and syllabic r), but ć is a letter in its own right
+	"sh": {
+		marks: mark_acute + mark_grave + mark_macron + mark_tilde + mark_double_grave + mark_inv_breve,
+		bases: "aeiourAEIOURаеиоурАЕИОУР",
+	},
+	// Lithuanian marks stress and tone with accents - none of its letters use them
+	"lt": {marks: mark_acute + mark_grave + mark_tilde},
+	// Arabic leaves out the short vowels and other harakat, and the tatweel
+	"ar": {
+		marks:   "\u064b\u064c\u064d\u064e\u064f\u0650\u0651\u0652\u0670",
+		replace: strings.NewReplacer("\u0640", "", "\u0671", "\u0627"),
+	},
+	// Hebrew leaves out the niqqud and cantillation marks
+	"he": {marks: hebrewPoints()},
+}
+
+func hebrewPoints() string {
+	// the Hebrew points and accents, other than the punctuation in the same block
+	var points strings.Builder
+	for r := '\u0591'; r <= '\u05c7'; r++ {
+		switch r {
+		case '\u05be', '\u05c0', '\u05c3', '\u05c6':
+			continue
+		}
+		points.WriteRune(r)
+	}
+	return points.String()
+}
+
+func getEntryName(word string, langCode string) string {
+	// apply the language's entry-name rule, if it has one, e.g. for Latin "lūna" is on the page "luna"
+	rule, ok := entryNameRules[langCode]
+	if !ok {
+		return word
+	}
+
+	// work on the decomposed text, so that e.g. "ū" is "u" followed by a combining macron
+	decomposed := norm.NFD.String(word)
+	var name strings.Builder
+	var base rune
+	for _, r := range decomposed {
+		if strings.ContainsRune(rule.marks, r) && (rule.bases == "" || strings.ContainsRune(rule.bases, base)) {
+			continue
+		}
+		if !unicode.Is(unicode.Mn, r) {
+			base = r
+		}
+		name.WriteRune(r)
+	}
+	entryName := norm.NFC.String(name.String())
+	if rule.replace != nil {
+		entryName = rule.replace.Replace(entryName)
+	}
+	return entryName
+}
@@ -0,0 +1,52 @@
+package wiktionary
+
+import "testing"
+
+func TestGetEntryName(t *testing.T) {
+	tests := []struct {
+		word     string
+		langCode string
+		expected string
+	}{
+		{"lūna", "la", "luna"},
+		{"Rōma", "la", "Roma"},
+		{"ăqua", "la", "aqua"},
+		{"poēta", "la", "poeta"},
+		{"grēne", "ang", "grene"},
+		{"ċiriċe", "ang", "cirice"},
+		{"молоко́", "ru", "молоко"},
+		{"ёлка", "ru", "ёлка"},
+		{"йо́гурт", "ru", "йогурт"},
+		{"вода́", "uk", "вода"},
+		{"ᾱ̓ήρ", "grc", "ἀήρ"},
+		{"λόγος", "grc", "λόγος"},
+		{"jèzik", "sh", "jezik"},
+		{"kȕća", "sh", "kuća"},
+		{"ćȁl", "sh", "ćal"},
+		{"rañkà", "lt", "ranka"},
+		{"كِتَاب", "ar", "كتاب"},
+		{"ٱلْكِتَابُ", "ar", "الكتاب"},
+		{"שָׁלוֹם", "he", "שלום"},
+		// languages without a rule are left as they are
+		{"grēne", "en", "grēne"},
+		{"café", "fr", "café"},
+	}
+	for _, test := range tests {
+		if name := getEntryName(test.word, test.langCode); name != test.expected {
+			t.Fatalf(`getEntryName(%q, %q): expected %q, got %q`, test.word, test.langCode, test.expected, name)
+		}
+	}
+}
+
+func TestEntryNameLookup(t *testing.T) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{"luna": "==Latin==\n\n===Noun===\n{{la-noun}}\n\n# moon"}
+	lw, err := (&Client{}).GetWordWithOptions("lūna", "la", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	if lw.Word != "lūna" || lw.Title != "luna" || lw.Meaning != "moon" {
+		t.Fatalf(`GetWordWithOptions: expected "lūna" from page "luna", got %q from %q, meaning %q`, lw.Word, lw.Title, lw.Meaning)
+	}
+}
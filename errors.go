@@ -0,0 +1,149 @@
+package wiktionary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CanceledError is returned when a lookup's context is cancelled or its deadline passes
+// Err is the context's error, so errors.Is(err, context.Canceled) and
+// errors.Is(err, context.DeadlineExceeded) work as expected
+type CanceledError struct {
+	Word     string
+	LangCode string
+	Err      error
+}
+
+func (e *CanceledError) Error() string {
+	if e.Word == "" {
+		return fmt.Sprintf("Lookup cancelled: %v", e.Err)
+	}
+	return fmt.Sprintf("Lookup of word '%s' (%s) cancelled: %v", e.Word, e.LangCode, e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+func checkCanceled(ctx context.Context, word string, langCode string) error {
+	// return a CanceledError if the context is done, otherwise nil
+	if err := ctx.Err(); err != nil {
+		return &CanceledError{Word: word, LangCode: langCode, Err: err}
+	}
+	return nil
+}
+
+// HttpStatusError is returned when the API responds with an HTTP error, or reports that it is
+// lagging, and retrying hasn't helped
+type HttpStatusError struct {
+	StatusCode int
+	Status     string
+	MaxLag     bool // the API refused the request because its replicas are lagging
+}
+
+func (e *HttpStatusError) Error() string {
+	if e.MaxLag {
+		return "Wiktionary API is lagging, request refused"
+	}
+	return "Wiktionary API returned " + e.Status
+}
+
+// sentinel errors for the ways a lookup can fail - use errors.Is to test for them, or errors.As
+// with the struct types below for the details
+var (
+	ErrPageNotFound        = errors.New("page not found on Wiktionary")
+	ErrLanguageNotOnPage   = errors.New("language not on page")
+	ErrUnknownLanguageCode = errors.New("unknown language code")
+	ErrRevisionMismatch    = errors.New("revision is not of the page requested")
+)
+
+// PageNotFoundError is returned when there is no page for a word
+type PageNotFoundError struct {
+	Word  string
+	Title string // the page title looked up, e.g. "Reconstruction:Proto-Germanic/raudaz"
+	Err   error  // the APIError, if the API reported the page missing
+}
+
+func (e *PageNotFoundError) Error() string {
+	return fmt.Sprintf("No Wiktionary page for word '%s'", e.Word)
+}
+
+func (e *PageNotFoundError) Is(target error) bool {
+	return target == ErrPageNotFound
+}
+
+func (e *PageNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// LanguageNotOnPageError is returned when a word's page exists, but has no entry for the
+// requested language - Languages holds the codes of the languages which are on the page
+type LanguageNotOnPageError struct {
+	Word      string
+	LangCode  string
+	Languages []string
+}
+
+func (e *LanguageNotOnPageError) Error() string {
+	return fmt.Sprintf("Word '%s' exists on Wiktionary, but not for %s", e.Word, getLanguageFromCode(e.LangCode))
+}
+
+func (e *LanguageNotOnPageError) Is(target error) bool {
+	return target == ErrLanguageNotOnPage
+}
+
+// UnknownLanguageCodeError is returned when a language code isn't one Wiktionary uses, nor
+// the name of a language - Suggestions holds the codes of the closest matches, if any
+type UnknownLanguageCodeError struct {
+	LangCode    string
+	Suggestions []string
+}
+
+func (e *UnknownLanguageCodeError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("Unknown language code '%s'", e.LangCode)
+	}
+	var suggestions []string
+	for _, code := range e.Suggestions {
+		suggestions = append(suggestions, fmt.Sprintf("%s (%s)", code, getLanguageFromCode(code)))
+	}
+	return fmt.Sprintf("Unknown language code '%s' - did you mean %s?", e.LangCode, strings.Join(suggestions, ", "))
+}
+
+func (e *UnknownLanguageCodeError) Is(target error) bool {
+	return target == ErrUnknownLanguageCode
+}
+
+// RevisionMismatchError is returned when a lookup is pinned to a revision which belongs to a
+// different page from the word's - PageTitle is the title of the page the revision is of
+type RevisionMismatchError struct {
+	Word       string
+	Title      string // the page title looked up
+	RevisionId int64
+	PageTitle  string
+}
+
+func (e *RevisionMismatchError) Error() string {
+	return fmt.Sprintf("Revision %d is of page '%s', not of the page for word '%s'", e.RevisionId, e.PageTitle, e.Word)
+}
+
+func (e *RevisionMismatchError) Is(target error) bool {
+	return target == ErrRevisionMismatch
+}
+
+// APIError is returned when the MediaWiki API reports an error, e.g. Code "missingtitle" or
+// "ratelimited" - a missing page also matches ErrPageNotFound
+type APIError struct {
+	Code string
+	Info string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Wiktionary API error '%s': %s", e.Code, e.Info)
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == ErrPageNotFound && e.Code == "missingtitle"
+}
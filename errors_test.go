@@ -0,0 +1,108 @@
+package wiktionary
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestPageNotFound(t *testing.T) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{"green": testWikitext}
+	_, err := (&Client{}).GetWordWithOptions("red", "en", options)
+	if !errors.Is(err, ErrPageNotFound) {
+		t.Fatalf(`GetWordWithOptions: expected ErrPageNotFound, got %q`, err)
+	}
+	var notFound *PageNotFoundError
+	if !errors.As(err, &notFound) || notFound.Word != "red" {
+		t.Fatalf(`GetWordWithOptions: expected a PageNotFoundError for "red", got %q`, err)
+	}
+
+	// the API reports a missing page on the page itself
+	server, _ := newTestApi(t, map[string]string{"green": testWikitext})
+	_, err = (&Client{BaseUrl: server.URL}).GetWord("*raudaz", "gem-pro")
+	if !errors.Is(err, ErrPageNotFound) {
+		t.Fatalf(`Client.GetWord: expected ErrPageNotFound, got %q`, err)
+	}
+	expected := "Reconstruction:Proto-Germanic/raudaz"
+	if !errors.As(err, &notFound) || notFound.Title != expected {
+		t.Fatalf(`Client.GetWord: expected a PageNotFoundError for %q, got %q`, expected, err)
+	}
+
+	// or, from some actions, with an error object
+	var apiErr *APIError
+	err = &PageNotFoundError{Word: "red", Err: &APIError{Code: "missingtitle"}}
+	if !errors.As(err, &apiErr) || apiErr.Code != "missingtitle" {
+		t.Fatalf(`PageNotFoundError: expected to unwrap to an APIError, got %q`, err)
+	}
+	if !errors.Is(&APIError{Code: "missingtitle"}, ErrPageNotFound) {
+		t.Fatalf(`APIError: expected "missingtitle" to match ErrPageNotFound`)
+	}
+}
+
+func TestLanguageNotOnPage(t *testing.T) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{"green": testWikitext}
+	_, err := (&Client{}).GetWordWithOptions("green", "fr", options)
+	if !errors.Is(err, ErrLanguageNotOnPage) {
+		t.Fatalf(`GetWordWithOptions: expected ErrLanguageNotOnPage, got %q`, err)
+	}
+	var notOnPage *LanguageNotOnPageError
+	if !errors.As(err, &notOnPage) {
+		t.Fatalf(`GetWordWithOptions: expected a LanguageNotOnPageError, got %q`, err)
+	}
+	expected := []string{"en", "nl"}
+	if !reflect.DeepEqual(notOnPage.Languages, expected) {
+		t.Fatalf(`LanguageNotOnPageError.Languages: expected %v, got %v`, expected, notOnPage.Languages)
+	}
+	if errors.Is(err, ErrPageNotFound) {
+		t.Fatalf(`GetWordWithOptions: did not expect ErrPageNotFound`)
+	}
+}
+
+func TestUnknownLanguageCode(t *testing.T) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{"green": testWikitext}
+	_, err := (&Client{}).GetWordWithOptions("green", "xx-nonsense", options)
+	if !errors.Is(err, ErrUnknownLanguageCode) {
+		t.Fatalf(`GetWordWithOptions: expected ErrUnknownLanguageCode, got %q`, err)
+	}
+	var unknown *UnknownLanguageCodeError
+	if !errors.As(err, &unknown) || unknown.LangCode != "xx-nonsense" {
+		t.Fatalf(`GetWordWithOptions: expected an UnknownLanguageCodeError, got %q`, err)
+	}
+
+	err = ReadDump("testdata/dump.xml", DumpOptions{Languages: []string{"en", "xx-nonsense"}}, func(LanguageWord) error { return nil })
+	if !errors.Is(err, ErrUnknownLanguageCode) {
+		t.Fatalf(`ReadDump: expected ErrUnknownLanguageCode, got %q`, err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"error":{"code":"readonly","info":"The wiki is currently in read-only mode."}}`))
+	}))
+	defer server.Close()
+
+	_, err := (&Client{BaseUrl: server.URL}).GetWord("green", "en")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "readonly" {
+		t.Fatalf(`Client.GetWord: expected an APIError with code "readonly", got %q`, err)
+	}
+	if errors.Is(err, ErrPageNotFound) {
+		t.Fatalf(`Client.GetWord: did not expect ErrPageNotFound`)
+	}
+
+	// rendering reports the error too
+	_, err = ApiSource{BaseUrl: server.URL}.RenderText(context.Background(), "{{m|en|green}}", "green", "en")
+	if !errors.As(err, &apiErr) || apiErr.Code != "readonly" {
+		t.Fatalf(`ApiSource.RenderText: expected an APIError with code "readonly", got %q`, err)
+	}
+}
@@ -1,5 +1,7 @@
 package wiktionary
 
+import "context"
+
 type TreeNode struct {
 	Word         string       `json:"word"`
 	LanguageCode string       `json:"lang-code"`
@@ -7,13 +9,13 @@ type TreeNode struct {
 	Parent       *TreeNode    `json:"parent"`
 }
 
-func getEtymologyTree(word string, langCode string, languages []string) (TreeNode, error) {
+func (c *Client) getEtymologyTree(ctx context.Context, word string, langCode string, languages []string) (TreeNode, error) {
 
 	rootNode := TreeNode{}
 
 	// first fetch the available translations for the specified word
 	// this is likely to only include extant languages, so we'll need to pick up extinct ones later
-	translations, err := GetTranslations(word, langCode, languages)
+	translations, err := c.GetTranslationsContext(ctx, word, langCode, languages)
 	if err != nil {
 		return rootNode, err
 	}
@@ -28,7 +30,7 @@ func getEtymologyTree(word string, langCode string, languages []string) (TreeNod
 		var options WiktionaryOptions
 		options.RequiredSections = Sec_All
 		options.RequiredLanguages = AllLanguages
-		lw, _ := processWord(word, langCode, options) // if this returns an error, we will just have a nil entry in the data
+		lw, _ := c.GetWordWithOptionsContext(ctx, word, langCode, options) // if this returns an error, we will just have a nil entry in the data
 
 		node := TreeNode{
 			Word:         tr.Word,
@@ -61,7 +63,7 @@ func getAncestors(lw LanguageWord) []LinkedWord {
 
 	// for now, use the default Etymology (the first one)
 	for _, word := range lw.Etymologies[0].Words {
-		if word.Relationship == "inherited" {
+		if IsAncestor(word.Relationship) {
 			ancestors = append(ancestors, word)
 		}
 	}
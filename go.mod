@@ -2,4 +2,7 @@ module github.com/ianpattison-google/wiktionary-golang
 
 go 1.17
 
-require golang.org/x/net v0.4.0
+require (
+	golang.org/x/net v0.4.0
+	golang.org/x/text v0.13.0
+)
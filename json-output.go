@@ -3,17 +3,30 @@ package wiktionary
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"time"
 )
 
 type LanguageWord struct {
-	Word           string      `json:"word"`
-	Meaning        string      `json:"meaning,omitempty"`
-	LanguageName   string      `json:"lang"`
-	LanguageCode   string      `json:"lang-code"`
-	Pronunciations []string    `json:"pron,omitempty"`
-	Ipa            string      `json:"ipa,omitempty"`
-	Etymologies    []Etymology `json:"etym,omitempty"`
-	Anagrams       string      `json:"anag,omitempty"`
+	Word           string         `json:"word"`
+	Meaning        string         `json:"meaning,omitempty"`
+	LanguageName   string         `json:"lang"`
+	LanguageCode   string         `json:"lang-code"`
+	Pronunciations []string       `json:"pron,omitempty"`
+	Ipa            string         `json:"ipa,omitempty"`
+	Pronunciation  *Pronunciation `json:"pronunciation,omitempty"` // the pronunciation section, read from its templates
+	Etymologies    []Etymology    `json:"etym,omitempty"`
+	Anagrams       string         `json:"anag,omitempty"`
+	Title          string         `json:"title,omitempty"`    // the title of the page parsed, after normalization and redirects
+	Revision       *Revision      `json:"revision,omitempty"` // the revision of the page parsed, if the source reports it
+	OtherSections  []OtherSection `json:"other,omitempty"`    // sections at the language level which aren't parsed, e.g. See also
+}
+
+// Revision identifies the version of a Wiktionary page an entry was parsed from
+type Revision struct {
+	PageId     int64     `json:"page-id,omitempty"`
+	RevisionId int64     `json:"rev-id"`
+	Timestamp  time.Time `json:"timestamp"`
 }
 
 type Etymology struct {
@@ -24,48 +37,161 @@ type Etymology struct {
 	Parts            []PartOfSpeech `json:"parts,omitempty"`
 	Pronunciations   []string       `json:"pron,omitempty"`
 	Ipa              string         `json:"ipa,omitempty"`
+	Pronunciation    *Pronunciation `json:"pronunciation,omitempty"`
+	OtherSections    []OtherSection `json:"other,omitempty"`
+}
+
+// Pronunciation is a Pronunciation section, read from templates such as {{IPA}} and {{rhymes}}
+type Pronunciation struct {
+	Transcriptions []Transcription `json:"ipa,omitempty"`
+	Rhymes         []string        `json:"rhymes,omitempty"` // e.g. "ɛd", for words rhyming in -ɛd
+	Homophones     []string        `json:"homophones,omitempty"`
+	Hyphenation    []string        `json:"hyph,omitempty"` // the syllables, e.g. "dic", "tion", "a", "ry"
+	Audio          []Audio         `json:"audio,omitempty"`
+}
+
+// Transcription is a pronunciation in a given accent or dialect, or in all of them if none is given
+type Transcription struct {
+	Accents    []string `json:"accents,omitempty"`  // e.g. "UK" or "US", from {{a}} or a= on {{IPA}}
+	Phonemic   []string `json:"phonemic,omitempty"` // e.g. /ɹɛd/
+	Phonetic   []string `json:"phonetic,omitempty"` // e.g. [ɹʷɛd]
+	EnPR       []string `json:"enpr,omitempty"`     // the English respelling, from {{enPR}}
+	Qualifiers []string `json:"quals,omitempty"`
 }
 
+// Audio is a recording of a pronunciation, from {{audio}}
+type Audio struct {
+	File    string   `json:"file"`
+	Url     string   `json:"url,omitempty"` // the direct URL of the file on Wikimedia Commons
+	Caption string   `json:"caption,omitempty"`
+	Accents []string `json:"accents,omitempty"`
+}
+
+// RelationshipType is how a LinkedWord is related to the word - see relationships.go
+// it is a string, so it can still be compared with strings such as "inherited"
+type RelationshipType = string
+
 // relationships for LinkedWord
 const (
-	Root       string = "root"
-	Inherited  string = "inherited"
-	Cognate    string = "cognate"
-	Descendant string = "descendant"
+	Root                  RelationshipType = "root"
+	Inherited             RelationshipType = "inherited"
+	Borrowed              RelationshipType = "borrowed"
+	LearnedBorrowing      RelationshipType = "learned borrowing"
+	OrthographicBorrowing RelationshipType = "orthographic borrowing"
+	SemiLearnedBorrowing  RelationshipType = "semi-learned borrowing"
+	Derived               RelationshipType = "derived"
+	Calque                RelationshipType = "calque"
+	PartialCalque         RelationshipType = "partial calque"
+	SemanticLoan          RelationshipType = "semantic loan"
+	PhonoSemanticMatching RelationshipType = "phono-semantic matching"
+	Mention               RelationshipType = "mention"
+	Cognate               RelationshipType = "cognate"
+	Descendant            RelationshipType = "descendant"
 )
 
 type LinkedWord struct {
-	Relationship    string          `json:"type"`
-	Language        string          `json:"lang"`
-	Word            string          `json:"word"`
-	Meaning         string          `json:"meaning,omitempty"`
-	Transliteration string          `json:"translit,omitempty"`
-	Attributes      map[string]bool `json:"attrs,omitempty"`
+	Relationship    RelationshipType `json:"type"`
+	Language        string           `json:"lang"`
+	Word            string           `json:"word"`
+	Meaning         string           `json:"meaning,omitempty"`
+	Transliteration string           `json:"translit,omitempty"`
+	Attributes      map[string]bool  `json:"attrs,omitempty"` // e.g. "borrowed" - kept for older callers, Relationship says the same
 }
 
 type TranslatedWord struct {
-	Language        string `json:"lang"`
-	Word            string `json:"word"`
-	Transliteration string `json:"translit,omitempty"`
+	Language        string   `json:"lang"`
+	Word            string   `json:"word"`
+	Transliteration string   `json:"translit,omitempty"`
+	Alt             string   `json:"alt,omitempty"`
+	Script          string   `json:"sc,omitempty"`
+	Genders         []string `json:"g,omitempty"` // e.g. "m", "f" or "n", or "m-p" for masculine plural
+	Qualifiers      []string `json:"quals,omitempty"`
+	Literal         string   `json:"lit,omitempty"`    // the literal meaning, where it differs from the sense
+	Needed          bool     `json:"needed,omitempty"` // marked with {{t-needed}} - a translation is wanted but there is none yet
+	See             string   `json:"see,omitempty"`    // the page which has this translation, from {{trans-see}}
+}
+
+// TranslationTable is the translations of one sense of a part of speech, from a {{trans-top}} box
+type TranslationTable struct {
+	Gloss        string           `json:"gloss,omitempty"`
+	SenseId      string           `json:"sense-id,omitempty"` // the ID of the matching sense, if it can be found
+	Translations []TranslatedWord `json:"trans,omitempty"`
+	See          string           `json:"see,omitempty"`       // the page with the translations, for a {{trans-see}} table
+	Unchecked    bool             `json:"unchecked,omitempty"` // from {{checktrans-top}} - the translations need checking
+	Source       string           `json:"source,omitempty"`    // the page the table was read from, if it isn't the entry's own page
+	Error        string           `json:"error,omitempty"`     // why the page in See couldn't be read, if it couldn't
+	err          error
 }
 
 type PartOfSpeech struct {
-	Name         string            `json:"name"`
-	Headword     string            `json:"head,omitempty"`
-	Attributes   map[string]string `json:"attrs,omitempty"`
-	Meanings     []string          `json:"meanings,omitempty"`
-	Translations []TranslatedWord  `json:"trans,omitempty"`
-	Synonyms     string            `json:"syn,omitempty"`
-	Antonyms     string            `json:"ant,omitempty"`
+	Name              string                   `json:"name"`
+	Headword          string                   `json:"head,omitempty"`
+	Attributes        map[string]string        `json:"attrs,omitempty"`
+	Meanings          []string                 `json:"meanings,omitempty"` // the text of each top-level sense, labels and all
+	Senses            []Sense                  `json:"senses,omitempty"`
+	Translations      []TranslatedWord         `json:"trans,omitempty"` // the first table of translations, which is of the principal meaning
+	TranslationTables []TranslationTable       `json:"trans-tables,omitempty"`
+	Synonyms          string                   `json:"syn,omitempty"`
+	Antonyms          string                   `json:"ant,omitempty"`
+	Relations         map[string][]RelatedTerm `json:"rels,omitempty"`  // keyed by relation, e.g. "derived" or "hyponyms"
+	OtherSections     []OtherSection           `json:"other,omitempty"` // e.g. Usage notes, See also
+}
+
+// Sense is a single definition of a part of speech, with its usage examples, quotations and sub-senses
+type Sense struct {
+	Gloss      string      `json:"gloss"`
+	Labels     []string    `json:"labels,omitempty"` // e.g. "US" or "colloquial", from {{lb}} or a leading {{q}}
+	Id         string      `json:"id,omitempty"`     // from {{senseid}}, used to link translations to the sense
+	Examples   []Example   `json:"examples,omitempty"`
+	Quotations []Quotation `json:"quotes,omitempty"`
+	Senses     []Sense     `json:"senses,omitempty"`
+	text       string      // the gloss with its labels, as in PartOfSpeech.Meanings
+}
+
+// Example is a usage example of a sense, e.g. from {{ux}}
+type Example struct {
+	Text            string `json:"text"`
+	Translation     string `json:"trans,omitempty"`
+	Transliteration string `json:"translit,omitempty"`
+}
+
+// Quotation is a quotation which shows a sense in use - the fields come from a template such as
+// {{quote-book}}, or if the citation is plain text, it is kept in Source
+type Quotation struct {
+	Year        string `json:"year,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Translation string `json:"trans,omitempty"`
+}
+
+// RelatedTerm is a term listed in a relation section such as Derived terms or Hyponyms
+type RelatedTerm struct {
+	Language        string   `json:"lang"`
+	Word            string   `json:"word"`
+	Alt             string   `json:"alt,omitempty"`
+	Transliteration string   `json:"translit,omitempty"`
+	Gloss           string   `json:"gloss,omitempty"`
+	Qualifiers      []string `json:"quals,omitempty"`
+	Sense           string   `json:"sense,omitempty"` // the sense of the headword the term relates to, if given
+}
+
+// OtherSection is a section which has no parser of its own, kept as it is so that its content
+// can still be used - Wikitext holds its lines as written, Text the same lines rendered
+type OtherSection struct {
+	Name     string `json:"name"`
+	Wikitext string `json:"wikitext,omitempty"`
+	Text     string `json:"text,omitempty"`
 }
 
-func writeJson(word string, langCode string, lw *LanguageWord) error {
+func writeJson(dir string, word string, langCode string, lw *LanguageWord) error {
 	b, err := json.Marshal(lw)
 	if err != nil {
 		return err
 	}
 	// write file
-	fileName := langCode + "-" + word + ".json"
+	fileName := filepath.Join(dir, langCode+"-"+word+".json")
 	errf := os.WriteFile(fileName, b, 0666)
 	if errf != nil {
 		return errf
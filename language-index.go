@@ -0,0 +1,190 @@
+package wiktionary
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// other names a language is known by, which Wiktionary lists as alternatives to its
+// canonical name - the canonical names themselves are in languageCodes
+var languageAliases = map[string]string{
+	"Farsi":             "fa",
+	"Modern Greek":      "el",
+	"Gaelic":            "gd",
+	"Flemish":           "nl",
+	"Valencian":         "ca",
+	"Moldovan":          "ro",
+	"Moldavian":         "ro",
+	"Sinhala":           "si",
+	"Maldivian":         "dv",
+	"Divehi":            "dv",
+	"Odia":              "or",
+	"Panjabi":           "pa",
+	"Pushto":            "ps",
+	"Kiswahili":         "sw",
+	"Filipino":          "tl",
+	"Uighur":            "ug",
+	"Kirghiz":           "ky",
+	"Laotian":           "lo",
+	"Cambodian":         "km",
+	"Myanmar":           "my",
+	"Navaho":            "nv",
+	"Byelorussian":      "be",
+	"Belorussian":       "be",
+	"Old Church Slavic": "cu",
+	"Old Bulgarian":     "cu",
+	"Anglo-Saxon":       "ang",
+	"Classical Greek":   "grc",
+	"Mandarin Chinese":  "cmn",
+	"Putonghua":         "cmn",
+	"Serbian":           "sh",
+	"Croatian":          "sh",
+	"Bosnian":           "sh",
+	"Montenegrin":       "sh",
+	"Bokmål":            "nb",
+	"Nynorsk":           "nn",
+	"Ottoman":           "ota",
+	"Castilian":         "es",
+	"Scots Gaelic":      "gd",
+	"Irish Gaelic":      "ga",
+	"Manx Gaelic":       "gv",
+	"Brythonic":         "cel-bry-pro",
+	"Proto-Brittonic":   "cel-bry-pro",
+	"Modern Hebrew":     "he",
+	"Common Germanic":   "gem-pro",
+}
+
+// the index from a language name (canonical or alternative, in lower case) to its code
+var languageIndex map[string]string
+var languageIndexOnce sync.Once
+
+func getLanguageIndex() map[string]string {
+	// the index is only built the first time it is needed - canonical names win over aliases
+	languageIndexOnce.Do(func() {
+		languageIndex = make(map[string]string, len(languageCodes)+len(languageAliases))
+		for name, code := range languageAliases {
+			if _, ok := languageCodes[code]; ok {
+				languageIndex[strings.ToLower(name)] = code
+			}
+		}
+		for code, name := range languageCodes {
+			languageIndex[strings.ToLower(name)] = code
+		}
+	})
+	return languageIndex
+}
+
+func getCodeFromLanguage(name string) string {
+	// convert a language name to its code, e.g. for "English" return "en" - the name can be
+	// an alternative name such as "Farsi", in any case
+	return getLanguageIndex()[strings.ToLower(strings.TrimSpace(name))]
+}
+
+func resolveLanguage(codeOrName string) (string, error) {
+	// accept either a language code or a language name, e.g. "la" or "Latin", and return the
+	// code - anything else is an UnknownLanguageCodeError suggesting the closest matches
+	if _, ok := languageCodes[codeOrName]; ok {
+		return codeOrName, nil
+	}
+	if code := getCodeFromLanguage(codeOrName); code != "" {
+		return code, nil
+	}
+	return "", &UnknownLanguageCodeError{LangCode: codeOrName, Suggestions: suggestLanguages(codeOrName)}
+}
+
+// the most languages suggested for an unknown code
+const maxLanguageSuggestions = 5
+
+func suggestLanguages(codeOrName string) []string {
+	// find the codes of the languages whose code or name is closest to the one given, allowing
+	// about one typo in three letters
+	input := strings.ToLower(strings.TrimSpace(codeOrName))
+	if input == "" {
+		return nil
+	}
+	maxDistance := len([]rune(input)) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	best := make(map[string]int)
+	consider := func(candidate string, code string) {
+		d := editDistance(input, strings.ToLower(candidate))
+		if d > maxDistance {
+			return
+		}
+		if prev, ok := best[code]; !ok || d < prev {
+			best[code] = d
+		}
+	}
+	for code, name := range languageCodes {
+		consider(code, code)
+		consider(name, code)
+	}
+	for name, code := range languageAliases {
+		if _, ok := languageCodes[code]; ok {
+			consider(name, code)
+		}
+	}
+
+	codes := make([]string, 0, len(best))
+	for code := range best {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if best[codes[i]] != best[codes[j]] {
+			return best[codes[i]] < best[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	if len(codes) > maxLanguageSuggestions {
+		codes = codes[:maxLanguageSuggestions]
+	}
+	return codes
+}
+
+func editDistance(a string, b string) int {
+	// the Levenshtein distance between two strings, counted in runes
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func resolveLanguages(codesOrNames []string) ([]string, error) {
+	// resolve a list of languages given by code or name - AllLanguages is left as it is
+	if len(codesOrNames) > 0 && codesOrNames[0] == "all" {
+		return codesOrNames, nil
+	}
+	var codes []string
+	for _, codeOrName := range codesOrNames {
+		code, err := resolveLanguage(codeOrName)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
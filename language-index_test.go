@@ -0,0 +1,76 @@
+package wiktionary
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGetCodeFromLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"English", "en"},
+		{"english", "en"},
+		{" Latin ", "la"},
+		{"Proto-Indo-European", "ine-pro"},
+		{"Farsi", "fa"},
+		{"Anglo-Saxon", "ang"},
+		{"Croatian", "sh"},
+		{"Nonsense", ""},
+	}
+	for _, test := range tests {
+		if code := getCodeFromLanguage(test.name); code != test.expected {
+			t.Fatalf(`getCodeFromLanguage(%q): expected %q, got %q`, test.name, test.expected, code)
+		}
+	}
+}
+
+func TestResolveLanguage(t *testing.T) {
+	for _, codeOrName := range []string{"la", "Latin", "latin"} {
+		if code, err := resolveLanguage(codeOrName); err != nil || code != "la" {
+			t.Fatalf(`resolveLanguage(%q): expected "la", got %q, %q`, codeOrName, code, err)
+		}
+	}
+
+	// an unknown language suggests the closest matches
+	_, err := resolveLanguage("Latn")
+	var unknown *UnknownLanguageCodeError
+	if !errors.As(err, &unknown) || !errors.Is(err, ErrUnknownLanguageCode) {
+		t.Fatalf(`resolveLanguage: expected an UnknownLanguageCodeError, got %q`, err)
+	}
+	if len(unknown.Suggestions) == 0 || unknown.Suggestions[0] != "la" {
+		t.Fatalf(`resolveLanguage: expected "la" as the first suggestion for "Latn", got %v`, unknown.Suggestions)
+	}
+	if !strings.Contains(err.Error(), "la (Latin)") {
+		t.Fatalf(`UnknownLanguageCodeError: expected the suggestions in the message, got %q`, err)
+	}
+	_, err = resolveLanguage("Englsh")
+	if !errors.As(err, &unknown) || len(unknown.Suggestions) == 0 || unknown.Suggestions[0] != "en" {
+		t.Fatalf(`resolveLanguage: expected "en" as the first suggestion for "Englsh", got %q`, err)
+	}
+
+	if d := editDistance("kitten", "sitting"); d != 3 {
+		t.Fatalf(`editDistance: expected 3, got %d`, d)
+	}
+}
+
+func TestLanguageByName(t *testing.T) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{"green": testWikitext}
+	lw, err := (&Client{}).GetWordWithOptions("green", "Dutch", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	if lw.LanguageCode != "nl" || lw.Meaning != "A Dutch word." {
+		t.Fatalf(`GetWordWithOptions: expected the Dutch entry, got %q with meaning %q`, lw.LanguageCode, lw.Meaning)
+	}
+
+	options.PageLanguages = []string{"English"}
+	words, err := (&Client{}).GetAllLanguagesWithOptions("green", options)
+	if _, ok := words["en"]; err != nil || len(words) != 1 || !ok {
+		t.Fatalf(`GetAllLanguagesWithOptions: expected only English, got %v, %q`, words, err)
+	}
+}
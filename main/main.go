@@ -19,7 +19,7 @@ func main() {
 		word = os.Args[1]
 	}
 	if len(os.Args) > 2 {
-		langCode = os.Args[2] // capture the language if specified
+		langCode = os.Args[2] // capture the language if specified - either its code or its name, e.g. la or Latin
 	}
 	if len(os.Args) > 3 && os.Args[3] == "-e" {
 		etymTree = true // the -e flag will produce an etymology tree for the given word
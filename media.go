@@ -0,0 +1,113 @@
+package wiktionary
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// media files such as the audio in {{audio}} are kept on Wikimedia Commons
+const defaultMediaUrl = "https://upload.wikimedia.org/wikipedia/commons"
+
+// MediaUrl returns the direct URL of a file on Wikimedia Commons, e.g. "En-us-red.ogg"
+func MediaUrl(file string) string {
+	return getMediaUrl(defaultMediaUrl, file)
+}
+
+func getMediaUrl(baseUrl string, file string) string {
+	// Commons stores each file under the first one and two hex digits of the MD5 hash of its name,
+	// e.g. Example.jpg is at a/a9/Example.jpg
+	name := getMediaFileName(file)
+	if name == "" {
+		return ""
+	}
+	sum := md5.Sum([]byte(name))
+	hash := hex.EncodeToString(sum[:])
+	return strings.TrimSuffix(baseUrl, "/") + "/" + hash[:1] + "/" + hash[:2] + "/" + url.PathEscape(name)
+}
+
+func getMediaFileName(file string) string {
+	// the name of a file as Commons stores it - without any "File:" prefix, with underscores for
+	// spaces, and starting with a capital letter
+	file = strings.TrimSpace(file)
+	for _, prefix := range []string{"File:", "Image:", "Media:"} {
+		file = strings.TrimPrefix(file, prefix)
+	}
+	file = strings.ReplaceAll(strings.TrimSpace(file), " ", "_")
+	r, size := utf8.DecodeRuneInString(file)
+	if r == utf8.RuneError {
+		return ""
+	}
+	return string(unicode.ToUpper(r)) + file[size:]
+}
+
+func (c *Client) DownloadAudio(audio Audio, dir string) (string, error) {
+	return c.DownloadAudioContext(context.Background(), audio, dir)
+}
+
+func (c *Client) DownloadAudioContext(ctx context.Context, audio Audio, dir string) (string, error) {
+	// save an audio file in the given directory, returning its path - a file which is already
+	// there isn't downloaded again
+	name := getMediaFileName(audio.File)
+	if name == "" {
+		return "", fmt.Errorf("No file name for audio '%s'", audio.File)
+	}
+	fileName := filepath.Join(dir, filepath.Base(name))
+	if _, err := os.Stat(fileName); err == nil {
+		return fileName, nil
+	}
+
+	mediaUrl := c.MediaUrl
+	if mediaUrl == "" {
+		mediaUrl = defaultMediaUrl
+	}
+	userAgent := c.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	httpClient := c.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	transport := c.Transport
+	if transport == nil {
+		transport = defaultTransport
+	}
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", getMediaUrl(mediaUrl, name), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", userAgent)
+		return req, nil
+	}
+	body, err := transport.do(ctx, httpClient, newRequest)
+	if err != nil {
+		if errc := checkCanceled(ctx, audio.File, ""); errc != nil {
+			return "", errc
+		}
+		return "", err
+	}
+
+	// write to a temporary file first, so that a failed write doesn't leave part of a file behind
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", err
+	}
+	tmpName := fileName + ".tmp"
+	if err := os.WriteFile(tmpName, body, 0666); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmpName, fileName); err != nil {
+		os.Remove(tmpName)
+		return "", err
+	}
+	return fileName, nil
+}
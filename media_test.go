@@ -0,0 +1,70 @@
+package wiktionary
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMediaUrl(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"Example.jpg", "https://upload.wikimedia.org/wikipedia/commons/a/a9/Example.jpg"},
+		// the name is normalized before it's hashed
+		{"File:example.jpg", "https://upload.wikimedia.org/wikipedia/commons/a/a9/Example.jpg"},
+		{"En-us-red.ogg", "https://upload.wikimedia.org/wikipedia/commons/c/c9/En-us-red.ogg"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if url := MediaUrl(test.file); url != test.expected {
+			t.Fatalf(`MediaUrl(%q): expected %q, got %q`, test.file, test.expected, url)
+		}
+	}
+}
+
+func TestDownloadAudio(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/c/c9/En-us-red.ogg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("audio data"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := &Client{MediaUrl: server.URL, Transport: &Transport{MaxRetries: -1}}
+	audio := Audio{File: "En-us-red.ogg"}
+	fileName, err := client.DownloadAudio(audio, dir)
+	if err != nil {
+		t.Fatalf(`Error from Client.DownloadAudio: %q`, err)
+	}
+	if fileName != filepath.Join(dir, "En-us-red.ogg") {
+		t.Fatalf(`Client.DownloadAudio: expected the file in %q, got %q`, dir, fileName)
+	}
+	if b, _ := os.ReadFile(fileName); string(b) != "audio data" {
+		t.Fatalf(`Client.DownloadAudio: expected the file to hold the audio, got %q`, b)
+	}
+
+	// a file which has already been downloaded isn't fetched again
+	if _, err := client.DownloadAudio(audio, dir); err != nil || len(paths) != 1 {
+		t.Fatalf(`Client.DownloadAudio: expected no second request, got %d requests and error %v`, len(paths), err)
+	}
+
+	// a missing file is an error, and leaves nothing behind
+	_, err = client.DownloadAudio(Audio{File: "Missing.ogg"}, dir)
+	var statusErr *HttpStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf(`Client.DownloadAudio: expected a 404 error, got %v`, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Missing.ogg")); err == nil {
+		t.Fatalf(`Client.DownloadAudio: expected no file for a missing audio file`)
+	}
+}
@@ -0,0 +1,234 @@
+package wiktionary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// a PageSource supplies the raw wikitext for a word's Wiktionary page
+type PageSource interface {
+	GetWikitext(ctx context.Context, word string, langCode string) (string, error)
+}
+
+// a RevisionSource can also fetch a given revision of a page, and report which revision it
+// fetched - a revisionId of 0 means the latest
+type RevisionSource interface {
+	GetRevision(ctx context.Context, word string, langCode string, revisionId int64) (*PageResponse, error)
+}
+
+// a TextRenderer can also render wikitext templates into HTML, as the action=parse API does
+// sources which don't implement it (e.g. offline sources) will leave the templates unexpanded
+type TextRenderer interface {
+	RenderText(ctx context.Context, text string, word string, langCode string) (string, error)
+}
+
+const defaultApiUrl = "https://en.wiktionary.org/w/api.php"
+
+// Wikimedia asks API clients to identify themselves with a User-Agent
+const defaultUserAgent = "wiktionary-golang (https://github.com/ianpattison-google/wiktionary-golang)"
+
+// ApiSource fetches pages from a live MediaWiki API - by default en.wiktionary.org, but
+// BaseUrl can point at any mirror which exposes the same api.php
+type ApiSource struct {
+	BaseUrl    string
+	HttpClient *http.Client // defaults to http.DefaultClient
+	UserAgent  string
+	Transport  *Transport // rate limiting, retries and timeouts - a shared default if nil
+}
+
+func (s ApiSource) GetWikitext(ctx context.Context, word string, langCode string) (string, error) {
+	page, err := s.GetPage(ctx, word, langCode)
+	if err != nil {
+		return "", err
+	}
+	return page.Wikitext, nil
+}
+
+// GetPage returns the wikitext for a word's page, along with the page and revision IDs,
+// and any normalization, redirects or warnings the API reported
+func (s ApiSource) GetPage(ctx context.Context, word string, langCode string) (*PageResponse, error) {
+	return s.GetRevision(ctx, word, langCode, 0)
+}
+
+// GetRevision is GetPage for a given revision of the page, or the latest if revisionId is 0
+func (s ApiSource) GetRevision(ctx context.Context, word string, langCode string, revisionId int64) (*PageResponse, error) {
+	return getWordDataFromWiktionary(ctx, s, word, langCode, revisionId)
+}
+
+func (s ApiSource) RenderText(ctx context.Context, text string, word string, langCode string) (string, error) {
+	parsed, err := s.Parse(ctx, text, word, langCode)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Text, nil
+}
+
+// Parse renders wikitext as it would appear on a word's page, returning the HTML along with
+// any warnings the API reported
+func (s ApiSource) Parse(ctx context.Context, text string, word string, langCode string) (*ParseResponse, error) {
+	return getTextFromApi(ctx, s, text, word, langCode)
+}
+
+func (s ApiSource) call(ctx context.Context, params url.Values, post bool) ([]byte, error) {
+	// make a request to the API with the given parameters, and return the body of the response
+	apiUrl := s.BaseUrl
+	if apiUrl == "" {
+		apiUrl = defaultApiUrl
+	}
+	transport := s.Transport
+	if transport == nil {
+		transport = defaultTransport
+	}
+	if maxLag := transport.maxLag(); maxLag > 0 {
+		params.Set("maxlag", strconv.Itoa(maxLag))
+	}
+	userAgent := s.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	httpClient := s.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	// each attempt needs a fresh request, since the body of a POST is consumed when it's sent
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		var req *http.Request
+		var err error
+		if post {
+			req, err = http.NewRequestWithContext(ctx, "POST", apiUrl, strings.NewReader(params.Encode()))
+			if err == nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+		} else {
+			req, err = http.NewRequestWithContext(ctx, "GET", apiUrl+"?"+params.Encode(), nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", userAgent)
+		return req, nil
+	}
+	return transport.do(ctx, httpClient, newRequest)
+}
+
+// DirSource reads pages from a directory of saved .wikitext files, named in the same way
+// as the files written by processWord, e.g. "en-red.wikitext"
+type DirSource struct {
+	Dir string
+}
+
+func (s DirSource) GetWikitext(ctx context.Context, word string, langCode string) (string, error) {
+	// look for the file saved for this language first
+	data, err := os.ReadFile(filepath.Join(s.Dir, langCode+"-"+word+".wikitext"))
+	if err == nil {
+		return string(data), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	// the file holds the whole page, so a file saved for another language will do just as well
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return "", err
+	}
+	suffix := "-" + word + ".wikitext"
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		// make sure the prefix is a whole language code, not part of a hyphenated word
+		if _, ok := languageCodes[strings.TrimSuffix(name, suffix)]; ok {
+			data, err := os.ReadFile(filepath.Join(s.Dir, name))
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		}
+	}
+
+	return "", &PageNotFoundError{Word: word, Title: getPageTitle(word, langCode)}
+}
+
+// MapSource holds pages in memory, keyed by page title, e.g. "red" or "Reconstruction:Proto-Germanic/raudaz"
+type MapSource map[string]string
+
+func (s MapSource) GetWikitext(ctx context.Context, word string, langCode string) (string, error) {
+	if wikitext, ok := s[getPageTitle(word, langCode)]; ok {
+		return wikitext, nil
+	}
+	return "", &PageNotFoundError{Word: word, Title: getPageTitle(word, langCode)}
+}
+
+func getPageWikitext(ctx context.Context, word string, langCode string, options WiktionaryOptions) (*PageResponse, error) {
+	// get the wikitext for a word from the cache if we can, otherwise from the page source,
+	// along with the title it resolved to and the revision it came from, if the source reports it
+	cache := options.Cache
+	title := getPageTitle(word, langCode)
+	if cache != nil {
+		if page, ok := cache.getPageData(title, options.RevisionId); ok {
+			return page, nil
+		}
+	}
+	page, err := getSourcePage(ctx, word, langCode, options.RevisionId, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// the API follows redirects itself, but other sources (and pinned revisions) don't
+	seen := map[string]bool{page.Title: true}
+	for {
+		target, ok := getRedirectTarget(page.Wikitext)
+		if !ok {
+			break
+		}
+		if len(seen) > maxRedirects || seen[target] {
+			return nil, fmt.Errorf("Too many redirects from word '%s', stopped at '%s'", word, target)
+		}
+		seen[target] = true
+		next, err := getSourcePage(ctx, getWordFromTitle(target), langCode, 0, options)
+		if err != nil {
+			return nil, err
+		}
+		next.Redirects = append(append(page.Redirects, TitleChange{From: page.Title, To: target}), next.Redirects...)
+		page = next
+	}
+
+	if cache != nil {
+		cache.putPageData(title, options.RevisionId, page)
+	}
+	return page, nil
+}
+
+func getSourcePage(ctx context.Context, word string, langCode string, revisionId int64, options WiktionaryOptions) (*PageResponse, error) {
+	// get a page from the page source - only some sources can fetch a given revision
+	source := getSource(options)
+	if revisionSource, ok := source.(RevisionSource); ok {
+		return revisionSource.GetRevision(ctx, word, langCode, revisionId)
+	}
+	if revisionId != 0 {
+		return nil, fmt.Errorf("Revision %d of word '%s' requested, but the page source only has the one version", revisionId, word)
+	}
+	wikitext, err := source.GetWikitext(ctx, word, langCode)
+	if err != nil {
+		return nil, err
+	}
+	return &PageResponse{Title: getPageTitle(word, langCode), Wikitext: wikitext}, nil
+}
+
+func getSource(options WiktionaryOptions) PageSource {
+	// if no source has been specified then use the live Wiktionary API
+	if options.Source == nil {
+		return ApiSource{}
+	}
+	return options.Source
+}
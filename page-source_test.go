@@ -0,0 +1,118 @@
+package wiktionary
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testWikitext = `==English==
+
+===Etymology===
+From Old English grēne.
+
+===Adjective===
+{{en-adj}}
+
+# Having green as its color.
+# Unripe.
+
+==Dutch==
+
+===Noun===
+{{nl-noun}}
+
+# A Dutch word.`
+
+func TestMapSource(t *testing.T) {
+	source := MapSource{"green": testWikitext}
+	wikitext, err := source.GetWikitext(context.Background(), "green", "en")
+	if err != nil {
+		t.Fatalf(`Error from MapSource.GetWikitext: %q`, err)
+	}
+	if wikitext != testWikitext {
+		t.Fatalf(`MapSource.GetWikitext: expected %q, got %q`, testWikitext, wikitext)
+	}
+	if _, err := source.GetWikitext(context.Background(), "red", "en"); err == nil {
+		t.Fatalf(`MapSource.GetWikitext: expected an error for a missing page`)
+	}
+
+	// reconstructions are keyed by their full page title
+	source = MapSource{"Reconstruction:Proto-Germanic/raudaz": "==Proto-Germanic=="}
+	if _, err := source.GetWikitext(context.Background(), "*raudaz", "gem-pro"); err != nil {
+		t.Fatalf(`Error from MapSource.GetWikitext: %q`, err)
+	}
+}
+
+func TestDirSource(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "en-green.wikitext"), []byte(testWikitext), 0666)
+	os.WriteFile(filepath.Join(dir, "en-evergreen.wikitext"), []byte("==English=="), 0666)
+	source := DirSource{Dir: dir}
+
+	wikitext, err := source.GetWikitext(context.Background(), "green", "en")
+	if err != nil {
+		t.Fatalf(`Error from DirSource.GetWikitext: %q`, err)
+	}
+	if wikitext != testWikitext {
+		t.Fatalf(`DirSource.GetWikitext: expected %q, got %q`, testWikitext, wikitext)
+	}
+
+	// a file saved for another language holds the same page
+	wikitext, err = source.GetWikitext(context.Background(), "green", "nl")
+	if err != nil {
+		t.Fatalf(`Error from DirSource.GetWikitext: %q`, err)
+	}
+	if wikitext != testWikitext {
+		t.Fatalf(`DirSource.GetWikitext: expected %q, got %q`, testWikitext, wikitext)
+	}
+
+	if _, err := source.GetWikitext(context.Background(), "reen", "en"); err == nil {
+		t.Fatalf(`DirSource.GetWikitext: expected an error for a missing page`)
+	}
+}
+
+func TestGetWordFromMapSource(t *testing.T) {
+	chdirTemp(t)
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.RequiredLanguages = AllLanguages
+	options.Source = MapSource{"green": testWikitext}
+
+	lw, err := GetWordWithOptions("green", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	expected := "Having green as its color."
+	if lw.Meaning != expected {
+		t.Fatalf(`lw.Meaning: expected %q, got %q`, expected, lw.Meaning)
+	}
+	if len(lw.Etymologies) != 1 || len(lw.Etymologies[0].Parts) != 1 {
+		t.Fatalf(`lw.Etymologies: expected 1 etymology with 1 part, got %v`, lw.Etymologies)
+	}
+	if len(lw.Etymologies[0].Parts[0].Meanings) != 2 {
+		t.Fatalf(`lw.Etymologies[0].Parts[0].Meanings: expected length 2, got %v`, len(lw.Etymologies[0].Parts[0].Meanings))
+	}
+
+	lw, err = GetWordWithOptions("green", "nl", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	expected = "A Dutch word."
+	if lw.Meaning != expected {
+		t.Fatalf(`lw.Meaning: expected %q, got %q`, expected, lw.Meaning)
+	}
+}
+
+func chdirTemp(t *testing.T) {
+	// processWord writes debug files to the working directory, so keep them out of the tree
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
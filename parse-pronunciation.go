@@ -0,0 +1,134 @@
+package wiktionary
+
+import "strings"
+
+// templates which say which accent or dialect the rest of a pronunciation line is for, e.g. {{a|UK}}
+var accentTemplates = map[string]bool{"a": true, "accent": true}
+
+func parsePronunciationLine(line string, pron *Pronunciation) {
+	// read the templates on a line of a Pronunciation section, e.g.
+	// "* {{a|US}} {{enPR|rĕd}}, {{IPA|en|/ɹɛd/|[ɹʷɛd]}}" - the accents apply to everything after them
+	var accents []string
+	var quals []string
+	var tr *Transcription
+	for _, node := range ParseWikitext(strings.TrimLeft(line, "*: ")) {
+		if node.Type != Node_Template {
+			continue
+		}
+		args := node.ArgMap()
+		switch {
+		case accentTemplates[node.Text]:
+			accents = append(accents, getAccentArgs(args)...)
+		case node.Text == "q" || node.Text == "qual" || node.Text == "qualifier" || node.Text == "i":
+			quals = append(quals, getPositionalArgs(args, 1)...)
+		case node.Text == "IPA" || node.Text == "enPR":
+			// each line has a transcription, with its phonemic and phonetic forms and any respelling
+			if tr == nil {
+				pron.Transcriptions = append(pron.Transcriptions, Transcription{
+					Accents:    append([]string(nil), accents...),
+					Qualifiers: append([]string(nil), quals...),
+				})
+				tr = &pron.Transcriptions[len(pron.Transcriptions)-1]
+			}
+			tr.Accents = appendNew(tr.Accents, getPronunciationArgs(args, "a", "aa")...)
+			tr.Qualifiers = appendNew(tr.Qualifiers, getPronunciationArgs(args, "q", "qq")...)
+			if node.Text == "enPR" {
+				tr.EnPR = append(tr.EnPR, getPronunciationArgs(args)...)
+				continue
+			}
+			for _, ipa := range getPronunciationArgs(args) {
+				if strings.HasPrefix(ipa, "[") {
+					tr.Phonetic = append(tr.Phonetic, ipa)
+				} else {
+					tr.Phonemic = append(tr.Phonemic, ipa)
+				}
+			}
+		case node.Text == "rhymes" || node.Text == "rhyme":
+			pron.Rhymes = append(pron.Rhymes, getPronunciationArgs(args)...)
+		case node.Text == "homophones" || node.Text == "homophone" || node.Text == "hmp":
+			pron.Homophones = append(pron.Homophones, getPronunciationArgs(args)...)
+		case node.Text == "hyph" || node.Text == "hyphenation":
+			// only the first hyphenation - any others come after an empty argument
+			for _, arg := range node.Args {
+				if !arg.Positional || arg.Name == "1" {
+					continue
+				}
+				syllable := strings.TrimSpace(arg.Raw)
+				if syllable == "" {
+					break
+				}
+				pron.Hyphenation = append(pron.Hyphenation, syllable)
+			}
+		case node.Text == "audio":
+			if file := strings.TrimSpace(args["2"]); file != "" {
+				audio := Audio{
+					File:    file,
+					Url:     MediaUrl(file),
+					Caption: getPlainText(args["3"]),
+					Accents: append([]string(nil), accents...),
+				}
+				audio.Accents = appendNew(audio.Accents, getPronunciationArgs(args, "a", "aa")...)
+				pron.Audio = append(pron.Audio, audio)
+			}
+		}
+	}
+}
+
+func getPronunciationArgs(args map[string]string, names ...string) []string {
+	// with no names, the values of a pronunciation template after its language code - otherwise
+	// the values of the named arguments, which may be comma-separated lists, e.g. a=UK,US
+	// any inline modifiers, e.g. "/ɹɛd/<q:rare>", are dropped
+	var vals []string
+	if len(names) == 0 {
+		from := 2
+		// some templates are written without the language, e.g. {{IPA|/ɹɛd/|lang=en}}
+		if _, ok := languageCodes[strings.TrimSpace(args["1"])]; !ok {
+			from = 1
+		}
+		vals = getPositionalArgs(args, from)
+	} else {
+		for _, name := range names {
+			if val := strings.TrimSpace(args[name]); val != "" {
+				vals = append(vals, strings.Split(val, ",")...)
+			}
+		}
+	}
+	var result []string
+	for _, val := range vals {
+		if i := strings.Index(val, "<"); i > 0 {
+			val = val[:i]
+		}
+		if val = strings.TrimSpace(val); val != "" {
+			result = append(result, val)
+		}
+	}
+	return result
+}
+
+func getAccentArgs(args map[string]string) []string {
+	// the accents in {{a}} - newer entries put the language code first, e.g. {{a|en|UK}}
+	accents := getPositionalArgs(args, 1)
+	if len(accents) > 1 {
+		if _, ok := languageCodes[accents[0]]; ok {
+			accents = accents[1:]
+		}
+	}
+	return accents
+}
+
+func appendNew(vals []string, more ...string) []string {
+	// append the values which aren't already there
+	for _, val := range more {
+		found := false
+		for _, v := range vals {
+			if v == val {
+				found = true
+				break
+			}
+		}
+		if !found {
+			vals = append(vals, val)
+		}
+	}
+	return vals
+}
@@ -0,0 +1,59 @@
+package wiktionary
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPronunciation(t *testing.T) {
+	wikitext := `==English==
+
+===Pronunciation===
+* {{a|en|UK}} {{IPA|en|/ɹɛd/|[ɹɛd]}}
+* {{a|US}} {{enPR|rĕd}}, {{IPA|en|/ɹɛd/|[ɹʷɛd]|q=rare}}
+* {{IPA|en|/ɹæd/|a=Scotland,Ireland}}
+* {{audio|en|En-us-red.ogg|Audio (US)|a=US}}
+* {{rhymes|en|ɛd|s=1}}
+* {{homophones|en|read<q:past tense>|redd}}
+* {{hyph|en|red||re|d}}
+
+===Etymology===
+From Old English rēad.
+
+===Adjective===
+{{en-adj}}
+
+# Having red as its colour.
+`
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{"red": wikitext}
+	lw, err := (&Client{}).GetWordWithOptions("red", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+
+	expected := &Pronunciation{
+		Transcriptions: []Transcription{
+			{Accents: []string{"UK"}, Phonemic: []string{"/ɹɛd/"}, Phonetic: []string{"[ɹɛd]"}},
+			{Accents: []string{"US"}, Phonemic: []string{"/ɹɛd/"}, Phonetic: []string{"[ɹʷɛd]"}, EnPR: []string{"rĕd"}, Qualifiers: []string{"rare"}},
+			{Accents: []string{"Scotland", "Ireland"}, Phonemic: []string{"/ɹæd/"}},
+		},
+		Rhymes:      []string{"ɛd"},
+		Homophones:  []string{"read", "redd"},
+		Hyphenation: []string{"red"},
+		Audio: []Audio{{
+			File:    "En-us-red.ogg",
+			Url:     "https://upload.wikimedia.org/wikipedia/commons/c/c9/En-us-red.ogg",
+			Caption: "Audio (US)",
+			Accents: []string{"US"},
+		}},
+	}
+	if !reflect.DeepEqual(lw.Pronunciation, expected) {
+		t.Fatalf(`GetWordWithOptions: expected pronunciation %+v, got %+v`, expected, lw.Pronunciation)
+	}
+	// the IPA is still the first phonemic transcription
+	if lw.Ipa != "/ɹɛd/" {
+		t.Fatalf(`GetWordWithOptions: expected the IPA /ɹɛd/, got %q`, lw.Ipa)
+	}
+}
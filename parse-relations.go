@@ -0,0 +1,201 @@
+package wiktionary
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// the relation sections, and the key each one's terms are stored under in PartOfSpeech.Relations
+var relationSections = map[string]string{
+	"Synonyms":         "synonyms",
+	"Antonyms":         "antonyms",
+	"Derived terms":    "derived",
+	"Related terms":    "related",
+	"Hyponyms":         "hyponyms",
+	"Hypernyms":        "hypernyms",
+	"Meronyms":         "meronyms",
+	"Holonyms":         "holonyms",
+	"Troponyms":        "troponyms",
+	"Coordinate terms": "coordinate",
+}
+
+// templates which list terms in columns, e.g. {{col3|en|red|green|blue}} - the first argument
+// is the language, and each of the rest a term
+var columnTemplates = map[string]bool{
+	"col": true, "col1": true, "col2": true, "col3": true, "col4": true, "col5": true, "col-auto": true,
+	"col-u": true, "col1-u": true, "col2-u": true, "col3-u": true, "col4-u": true, "col5-u": true,
+	"der2": true, "der3": true, "der4": true, "der5": true, "der3-u": true, "der4-u": true,
+	"rel2": true, "rel3": true, "rel4": true, "rel5": true, "rel3-u": true, "rel4-u": true,
+	"hyp2": true, "hyp3": true, "hyp4": true, "hyp5": true,
+}
+
+// templates which start and end a box of terms - the heading of the box says which sense they are for
+var relationBoxStarts = map[string]bool{
+	"rel-top": true, "der-top": true, "der-top3": true, "der-top4": true, "col-top": true, "hyp-top": true,
+}
+var relationBoxEnds = map[string]bool{
+	"rel-bottom": true, "der-bottom": true, "col-bottom": true, "hyp-bottom": true,
+}
+
+func parseRelationSection(ctx context.Context, lw *LanguageWord, section Section, scope sectionScope, options WiktionaryOptions) {
+	// read the terms in a relation section, e.g. Derived terms, into the part of speech it is under
+	part := scope.partOfSpeech(lw)
+	if part == nil {
+		return
+	}
+	relation := relationSections[strings.Trim(section.header, "=")]
+
+	var terms []RelatedTerm
+	boxSense := ""
+	for _, line := range section.lines {
+		var lineTerms []RelatedTerm
+		lineTerms, boxSense = parseRelationLine(line, lw.LanguageCode, boxSense)
+		terms = append(terms, lineTerms...)
+	}
+	if len(terms) == 0 {
+		return
+	}
+	if part.Relations == nil {
+		part.Relations = make(map[string][]RelatedTerm)
+	}
+	part.Relations[relation] = append(part.Relations[relation], terms...)
+}
+
+func parseRelationLine(line string, langCode string, boxSense string) ([]RelatedTerm, string) {
+	// read the terms from a single line - either a list item such as "* {{sense|colour}} {{l|en|red}}",
+	// or a template listing terms in columns - returning the sense of any box still open
+	var terms []RelatedTerm
+	sense := boxSense
+	var quals []string     // qualifiers waiting for the next term
+	afterSeparator := true // whether a qualifier now belongs to the next term rather than the last one
+
+	for _, node := range ParseWikitext(line) {
+		switch node.Type {
+		case Node_Text:
+			if strings.ContainsAny(node.Text, ",;/") {
+				afterSeparator = true
+			}
+		case Node_Link:
+			// a plain link to a term in the same language - but not e.g. [[Category:...]] or [[w:...]]
+			if node.Text == "" || strings.Contains(node.Text, ":") {
+				continue
+			}
+			terms = append(terms, RelatedTerm{Language: langCode, Word: node.Text, Qualifiers: quals, Sense: sense})
+			quals = nil
+			afterSeparator = false
+		case Node_Template:
+			args := node.ArgMap()
+			switch {
+			case relationBoxStarts[node.Text]:
+				boxSense = strings.TrimSpace(args["1"])
+				sense = boxSense
+			case relationBoxEnds[node.Text]:
+				boxSense = ""
+				sense = ""
+			case node.Text == "sense" || node.Text == "s":
+				sense = strings.Join(getPositionalArgs(args, 1), ", ")
+			case node.Text == "q" || node.Text == "qual" || node.Text == "qualifier" || node.Text == "i" || node.Text == "qf":
+				qualifiers := getPositionalArgs(args, 1)
+				// a qualifier straight after a term is for that term, e.g. "{{l|en|x}} {{q|dated}}"
+				if len(terms) > 0 && !afterSeparator {
+					last := &terms[len(terms)-1]
+					last.Qualifiers = append(last.Qualifiers, qualifiers...)
+				} else {
+					quals = append(quals, qualifiers...)
+				}
+			case node.Text == "lb" || node.Text == "lbl" || node.Text == "label":
+				quals = append(quals, getPositionalArgs(args, 2)...)
+			case node.Text == "l" || node.Text == "link" || node.Text == "l-self" || node.Text == "ll":
+				term := RelatedTerm{
+					Language:        strings.TrimSpace(args["1"]),
+					Word:            strings.TrimSpace(args["2"]),
+					Alt:             strings.TrimSpace(args["3"]),
+					Transliteration: args["tr"],
+					Gloss:           firstNonEmpty(args["4"], args["t"], args["gloss"]),
+					Qualifiers:      quals,
+					Sense:           sense,
+				}
+				quals = nil
+				afterSeparator = false
+				if term.Word != "" && term.Word != "-" {
+					terms = append(terms, term)
+				}
+			case columnTemplates[node.Text]:
+				terms = append(terms, parseColumnTemplate(&node, langCode, sense)...)
+			}
+		}
+	}
+	return terms, boxSense
+}
+
+func parseColumnTemplate(tmpl *Node, langCode string, sense string) []RelatedTerm {
+	// each argument after the language is a term, e.g. {{col3|en|red|green<q:dated>}}
+	// older templates such as {{der3}} may leave the language out
+	args := tmpl.ArgMap()
+	from := 2
+	if _, ok := languageCodes[strings.TrimSpace(args["1"])]; ok {
+		langCode = strings.TrimSpace(args["1"])
+	} else {
+		from = 1
+	}
+	if title := strings.TrimSpace(args["title"]); title != "" {
+		sense = title
+	}
+
+	var terms []RelatedTerm
+	for _, arg := range tmpl.Args {
+		if !arg.Positional {
+			continue
+		}
+		if pos, _ := strconv.Atoi(arg.Name); pos < from {
+			continue
+		}
+		term := parseColumnItem(strings.TrimSpace(arg.Raw), langCode)
+		if term.Word == "" {
+			continue
+		}
+		term.Sense = sense
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+func parseColumnItem(item string, langCode string) RelatedTerm {
+	// a term in a column template, which can have inline modifiers, e.g. "red<q:dated><t:a colour>"
+	term := RelatedTerm{Language: langCode}
+	if i := strings.Index(item, "<"); i >= 0 && strings.HasSuffix(item, ">") {
+		for _, modifier := range strings.Split(item[i+1:len(item)-1], "><") {
+			kv := strings.SplitN(modifier, ":", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "q", "qq":
+				term.Qualifiers = append(term.Qualifiers, kv[1])
+			case "t", "gloss":
+				term.Gloss = kv[1]
+			case "tr":
+				term.Transliteration = kv[1]
+			case "alt":
+				term.Alt = kv[1]
+			}
+		}
+		item = item[:i]
+	}
+	// an item may itself be a link, e.g. [[red]] or [[red|Red]]
+	if nodes := ParseWikitext(item); len(nodes) == 1 && nodes[0].Type == Node_Link {
+		item = nodes[0].Text
+	}
+	term.Word = strings.TrimSpace(item)
+	return term
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, val := range vals {
+		if val = strings.TrimSpace(val); val != "" {
+			return val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package wiktionary
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRelationSections(t *testing.T) {
+	wikitext := `==English==
+
+===Etymology===
+From Old English rēad.
+
+===Adjective===
+{{en-adj}}
+
+# Having red as its colour.
+
+====Synonyms====
+* {{sense|colour}} {{l|en|crimson}}, {{q|poetic}} {{l|en|sanguine}}
+* [[ruddy]] {{q|of the face}}
+
+====Hyponyms====
+{{col3|en|title=shades of red|scarlet|vermilion<q:bright><t:a vivid red>|[[carmine]]}}
+
+====Derived terms====
+{{der3|en|redden|redness}}
+
+====Related terms====
+{{rel-top|colour}}
+* {{l|fr|rouge||red}}
+{{rel-bottom}}
+* {{l|en|read|tr=-}}
+`
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{"red": wikitext}
+	lw, err := (&Client{}).GetWordWithOptions("red", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	part := lw.Etymologies[0].Parts[0]
+
+	expected := map[string][]RelatedTerm{
+		"synonyms": {
+			{Language: "en", Word: "crimson", Sense: "colour"},
+			{Language: "en", Word: "sanguine", Qualifiers: []string{"poetic"}, Sense: "colour"},
+			{Language: "en", Word: "ruddy", Qualifiers: []string{"of the face"}},
+		},
+		"hyponyms": {
+			{Language: "en", Word: "scarlet", Sense: "shades of red"},
+			{Language: "en", Word: "vermilion", Qualifiers: []string{"bright"}, Gloss: "a vivid red", Sense: "shades of red"},
+			{Language: "en", Word: "carmine", Sense: "shades of red"},
+		},
+		"derived": {
+			{Language: "en", Word: "redden"},
+			{Language: "en", Word: "redness"},
+		},
+		"related": {
+			{Language: "fr", Word: "rouge", Gloss: "red", Sense: "colour"},
+			{Language: "en", Word: "read", Transliteration: "-"},
+		},
+	}
+	for relation, terms := range expected {
+		if !reflect.DeepEqual(part.Relations[relation], terms) {
+			t.Fatalf(`GetWordWithOptions: expected %s %+v, got %+v`, relation, terms, part.Relations[relation])
+		}
+	}
+
+	// the synonyms are still kept as text too
+	if part.Synonyms == "" {
+		t.Fatalf(`GetWordWithOptions: expected the synonyms as text as well`)
+	}
+}
@@ -0,0 +1,189 @@
+package wiktionary
+
+import (
+	"context"
+	"strings"
+)
+
+// templates which label a sense rather than being part of its gloss
+var senseLabelTemplates = map[string]bool{"lb": true, "lbl": true, "label": true}
+var senseQualifierTemplates = map[string]bool{"q": true, "qual": true, "qualifier": true, "i": true, "qf": true}
+
+// templates for usage examples, e.g. {{ux|fr|Il est rouge.|He is red.}}, with the positional
+// arguments holding the text, translation and transliteration - {{ja-usex}} and {{zh-x}} have no
+// language code, and the second argument of {{ja-usex}} is the text in kana
+var exampleTemplates = map[string][3]string{
+	"ux": {"2", "3", "4"}, "uxi": {"2", "3", "4"}, "usex": {"2", "3", "4"},
+	"ja-usex": {"1", "3", "2"}, "zh-x": {"1", "2", ""},
+}
+
+// templates for quotations, e.g. {{quote-book|en|year=1851|author=Herman Melville|title=Moby-Dick|passage=...}}
+var quotationTemplates = map[string]bool{
+	"quote-book": true, "quote-journal": true, "quote-web": true, "quote-text": true, "quote-song": true,
+	"quote-newsgroup": true, "quote-av": true, "quote-hansard": true, "quote-video game": true, "quote": true,
+}
+
+func parseSenses(ctx context.Context, lw *LanguageWord, lines []string, options WiktionaryOptions) []Sense {
+	// build the tree of senses from the definition lines of a part of speech - "#" is a sense,
+	// "##" a sub-sense, "#:" a usage example, "#*" a quotation and "#*:" the quotation's text
+	var senses []Sense
+	for _, line := range lines {
+		depth := len(line) - len(strings.TrimLeft(line, "#"))
+		if depth == 0 {
+			continue
+		}
+		marker := strings.TrimLeft(line, "#")
+		parent := getSenseAtDepth(senses, depth)
+
+		switch {
+		case strings.HasPrefix(marker, "*:"):
+			// the text of the latest quotation, where it's on a line of its own
+			if parent != nil && len(parent.Quotations) > 0 {
+				quote := &parent.Quotations[len(parent.Quotations)-1]
+				text := strings.TrimSpace(strings.TrimLeft(marker, "*:"))
+				if strings.HasPrefix(marker, "*::") && quote.Text != "" {
+					quote.Translation = getPlainText(text)
+				} else {
+					quote.Text = getPlainText(text)
+				}
+			}
+		case strings.HasPrefix(marker, "*"):
+			if parent != nil {
+				parent.Quotations = append(parent.Quotations, parseQuotation(strings.TrimSpace(marker[1:])))
+			}
+		case strings.HasPrefix(marker, "::"):
+			// the translation of the latest usage example
+			if parent != nil && len(parent.Examples) > 0 {
+				parent.Examples[len(parent.Examples)-1].Translation = getPlainText(strings.TrimSpace(marker[2:]))
+			}
+		case strings.HasPrefix(marker, ":"):
+			if parent != nil {
+				parent.Examples = append(parent.Examples, parseExample(strings.TrimSpace(marker[1:])))
+			}
+		default:
+			sense := parseSense(ctx, lw, strings.TrimSpace(marker), options)
+			// a sense is a child of the latest sense one level up - if there is none (e.g. a page
+			// starting with "##"), it goes at the top level
+			if depth > 1 {
+				if up := getSenseAtDepth(senses, depth-1); up != nil {
+					up.Senses = append(up.Senses, sense)
+					continue
+				}
+			}
+			senses = append(senses, sense)
+		}
+	}
+	return senses
+}
+
+func getSenseAtDepth(senses []Sense, depth int) *Sense {
+	// the latest sense at the given depth, or the deepest there is if the tree isn't that deep
+	if len(senses) == 0 {
+		return nil
+	}
+	sense := &senses[len(senses)-1]
+	for d := 1; d < depth && len(sense.Senses) > 0; d++ {
+		sense = &sense.Senses[len(sense.Senses)-1]
+	}
+	return sense
+}
+
+func parseSense(ctx context.Context, lw *LanguageWord, line string, options WiktionaryOptions) Sense {
+	// read the labels and sense ID from a definition line, and render the rest as its gloss
+	var sense Sense
+	var gloss strings.Builder
+	var labelText []string
+	leading := true
+	for _, node := range ParseWikitext(line) {
+		if node.Type == Node_Template {
+			args := node.ArgMap()
+			switch {
+			case senseLabelTemplates[node.Text]:
+				sense.Labels = append(sense.Labels, getLabels(args)...)
+				labelText = append(labelText, expandOrJoin(expandLabelTemplate, args, getLabels(args)))
+				continue
+			case senseQualifierTemplates[node.Text] && leading:
+				quals := getPositionalArgs(args, 1)
+				sense.Labels = append(sense.Labels, quals...)
+				labelText = append(labelText, expandOrJoin(expandQualifierTemplate, args, quals))
+				continue
+			case node.Text == "senseid":
+				sense.Id = strings.TrimSpace(args["2"])
+				continue
+			}
+		}
+		if node.Type != Node_Comment && strings.TrimSpace(node.Raw) != "" {
+			leading = false
+		}
+		gloss.WriteString(node.Raw)
+	}
+	sense.Gloss, _ = getConvertedTextFromWiktionary(ctx, strings.TrimSpace(gloss.String()), lw.Word, lw.LanguageCode, options)
+
+	// the text as the whole line is shown, labels and all, for the flat list of meanings
+	sense.text = sense.Gloss
+	if len(labelText) > 0 {
+		sense.text = strings.TrimSpace(strings.Join(labelText, " ") + " " + sense.Gloss)
+	}
+	return sense
+}
+
+func getLabels(args map[string]string) []string {
+	// the labels in an {{lb}} template, without the "_" which only joins them
+	var labels []string
+	for _, label := range getPositionalArgs(args, 2) {
+		if label != "_" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+func expandOrJoin(expander templateExpander, args map[string]string, vals []string) string {
+	// render a label template, or if it can't be rendered here, just list its values
+	if text, ok := expander(args); ok {
+		return text
+	}
+	return "(" + strings.Join(vals, ", ") + ")"
+}
+
+func parseExample(line string) Example {
+	// a usage example is either a template such as {{ux|en|text|translation}}, or plain text
+	nodes := ParseWikitext(line)
+	if tmpl := findTemplate(nodes); tmpl != nil {
+		if pos, ok := exampleTemplates[tmpl.Text]; ok {
+			args := tmpl.ArgMap()
+			return Example{
+				Text:            getPlainText(args[pos[0]]),
+				Translation:     getPlainText(firstNonEmpty(args[pos[1]], args["t"], args["translation"])),
+				Transliteration: getPlainText(firstNonEmpty(args["tr"], args[pos[2]])),
+			}
+		}
+	}
+	return Example{Text: strings.TrimSpace(PlainText(nodes))}
+}
+
+func parseQuotation(line string) Quotation {
+	// a quotation is either a template such as {{quote-book}}, or a citation in plain text with
+	// the passage on the next line
+	nodes := ParseWikitext(line)
+	if tmpl := findTemplate(nodes); tmpl != nil && quotationTemplates[tmpl.Text] {
+		args := tmpl.ArgMap()
+		author := args["author"]
+		if author == "" && args["last"] != "" {
+			author = strings.TrimSpace(args["first"] + " " + args["last"])
+		}
+		return Quotation{
+			Year:        firstNonEmpty(args["year"], args["date"]),
+			Author:      getPlainText(author),
+			Title:       getPlainText(firstNonEmpty(args["title"], args["chapter"], args["work"])),
+			Text:        getPlainText(firstNonEmpty(args["passage"], args["text"], args["8"])),
+			Translation: getPlainText(firstNonEmpty(args["translation"], args["t"])),
+		}
+	}
+	return Quotation{Source: strings.TrimSpace(PlainText(nodes))}
+}
+
+func getPlainText(wikitext string) string {
+	// the text of some wikitext, without its markup
+	return strings.TrimSpace(PlainText(ParseWikitext(wikitext)))
+}
@@ -0,0 +1,84 @@
+package wiktionary
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSenses(t *testing.T) {
+	wikitext := `==English==
+
+===Etymology===
+From Old English grēne.
+
+===Adjective===
+{{en-adj}}
+
+# {{lb|en|colour}} Having green as its colour.{{senseid|en|colour}}
+#: {{ux|en|The grass is '''green'''.}}
+#* {{quote-book|en|year=1851|author=Herman Melville|title=Moby-Dick|passage=the '''green''' sea}}
+## {{q|of fruit}} Unripe.
+##: The apples are still green.
+# Inexperienced.
+#* '''1990''', A. Writer, ''A Book'':
+#*: He was very '''green'''.
+`
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{"green": wikitext}
+	lw, err := (&Client{}).GetWordWithOptions("green", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	part := lw.Etymologies[0].Parts[0]
+
+	expected := []Sense{
+		{
+			Gloss:      "Having green as its colour.",
+			Labels:     []string{"colour"},
+			Id:         "colour",
+			Examples:   []Example{{Text: "The grass is green."}},
+			Quotations: []Quotation{{Year: "1851", Author: "Herman Melville", Title: "Moby-Dick", Text: "the green sea"}},
+			Senses: []Sense{
+				{
+					Gloss:    "Unripe.",
+					Labels:   []string{"of fruit"},
+					Examples: []Example{{Text: "The apples are still green."}},
+					text:     "(of fruit) Unripe.",
+				},
+			},
+			text: "(colour) Having green as its colour.",
+		},
+		{
+			Gloss:      "Inexperienced.",
+			Quotations: []Quotation{{Source: "1990, A. Writer, A Book:", Text: "He was very green."}},
+			text:       "Inexperienced.",
+		},
+	}
+	if !reflect.DeepEqual(part.Senses, expected) {
+		t.Fatalf(`GetWordWithOptions: expected senses %+v, got %+v`, expected, part.Senses)
+	}
+
+	// the meanings are the top-level senses, labels and all
+	meanings := []string{"(colour) Having green as its colour.", "Inexperienced."}
+	if !reflect.DeepEqual(part.Meanings, meanings) || lw.Meaning != meanings[0] {
+		t.Fatalf(`GetWordWithOptions: expected meanings %q, got %q`, meanings, part.Meanings)
+	}
+}
+
+func TestExamples(t *testing.T) {
+	// each usage example template has its text and translation in its own arguments
+	examples := map[string]Example{
+		"{{ux|fr|Il est '''rouge'''.|He is red.}}":               {Text: "Il est rouge.", Translation: "He is red."},
+		"{{uxi|ru|Он '''красный'''.|He is red.|tr=On krasnyj.}}": {Text: "Он красный.", Translation: "He is red.", Transliteration: "On krasnyj."},
+		"{{ja-usex|'''赤い'''花|あかい はな|a red flower}}":              {Text: "赤い花", Translation: "a red flower", Transliteration: "あかい はな"},
+		"{{zh-x|紅色 的 花|a red flower}}":                           {Text: "紅色 的 花", Translation: "a red flower"},
+		"{{zh-x|紅色 的 花|t=a red flower|tr=hóngsè de huā}}":        {Text: "紅色 的 花", Translation: "a red flower", Transliteration: "hóngsè de huā"},
+		"The apples are still green.":                            {Text: "The apples are still green."},
+	}
+	for line, expected := range examples {
+		if example := parseExample(line); example != expected {
+			t.Fatalf(`parseExample(%q): expected %+v, got %+v`, line, expected, example)
+		}
+	}
+}
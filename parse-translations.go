@@ -0,0 +1,266 @@
+package wiktionary
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// templates for a single translation, e.g. {{t+|de|Haus|n}} - tt and tt+ are older names
+var translationTemplates = map[string]bool{
+	"t": true, "t+": true, "tt": true, "tt+": true, "t-check": true, "t+check": true,
+}
+
+func parseTranslationSection(ctx context.Context, lw *LanguageWord, section Section, scope sectionScope, options WiktionaryOptions) {
+	// read every table of translations in the section, following any which are on another page
+	part := scope.partOfSpeech(lw)
+	if part == nil {
+		return
+	}
+
+	var tables []TranslationTable
+	followed := make(map[string]bool)
+	for _, table := range readTranslationTables(section.lines, lw.Word, options) {
+		tables = append(tables, table)
+		if table.See != "" && !followed[table.See] {
+			followed[table.See] = true
+			found, err := followTranslationTable(ctx, lw, part.Name, table, options)
+			if err != nil {
+				// the translations couldn't be read, which is kept with the table pointing to them
+				// so that it isn't mistaken for there being none
+				tables[len(tables)-1].Error = err.Error()
+				tables[len(tables)-1].err = err
+			}
+			tables = append(tables, found...)
+		}
+	}
+
+	// the senses can only be matched for tables of this word, on its own page or its subpage
+	for i := range tables {
+		if tables[i].SenseId == "" && (tables[i].Source == "" || strings.HasPrefix(tables[i].Source, lw.Word+"/")) {
+			tables[i].SenseId = findSenseId(part.Senses, tables[i].Gloss)
+		}
+	}
+	part.TranslationTables = append(part.TranslationTables, tables...)
+
+	// the translations of the first table are of the principal meaning of the word - many words have
+	// translations of colloquial meanings which are not relevant
+	// the markers for missing translations, or translations elsewhere, stay in the table
+	if part.Translations == nil {
+		for _, table := range part.TranslationTables {
+			for _, tw := range table.Translations {
+				if tw.Word != "" {
+					part.Translations = append(part.Translations, tw)
+				}
+			}
+			if part.Translations != nil {
+				break
+			}
+		}
+	}
+}
+
+func readTranslationTables(lines []string, word string, options WiktionaryOptions) []TranslationTable {
+	// each table starts with {{trans-top|gloss}}, and the gloss says which sense of the word it translates
+	var tables []TranslationTable
+	var table *TranslationTable
+	for _, line := range lines {
+		nodes := ParseWikitext(line)
+		if tmpl := findTemplate(nodes); tmpl != nil && !strings.HasPrefix(line, "*") {
+			args := tmpl.ArgMap()
+			switch tmpl.Text {
+			case "trans-top", "trans-top-also", "checktrans-top", "ttbc-top":
+				tables = append(tables, TranslationTable{
+					Gloss:   strings.TrimSpace(args["1"]),
+					SenseId: strings.TrimSpace(args["id"]),
+				})
+				table = &tables[len(tables)-1]
+				if tmpl.Text != "trans-top" && tmpl.Text != "trans-top-also" {
+					table.Unchecked = true
+				}
+			case "trans-bottom", "checktrans-bottom", "ttbc-bottom":
+				table = nil
+			case "trans-see":
+				// the translations for this sense are on another page, e.g. {{trans-see|scarlet}}
+				// or {{trans-see|a gloss|target page}}
+				tables = append(tables, TranslationTable{
+					Gloss: strings.TrimSpace(args["1"]),
+					See:   firstNonEmpty(args["2"], args["1"]),
+				})
+				table = nil
+			case "see translation subpage":
+				// the translations are all on the word's subpage, e.g. "house/translations", under
+				// the part of speech given in the template, if it is given
+				see := firstNonEmpty(args["2"], args["page"], word+"/translations")
+				if pos := strings.TrimSpace(args["1"]); pos != "" && !strings.Contains(see, "#") {
+					see += "#" + pos
+				}
+				tables = append(tables, TranslationTable{See: see})
+				table = nil
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "*") {
+			continue
+		}
+		// translations outside a table, which older entries may have, go in a table with no gloss
+		if table == nil {
+			tables = append(tables, TranslationTable{})
+			table = &tables[len(tables)-1]
+		}
+		for _, tw := range parseTranslationLine(nodes) {
+			if languageRequired(options, tw.Language) {
+				table.Translations = append(table.Translations, tw)
+			}
+		}
+	}
+	return tables
+}
+
+func followTranslationTable(ctx context.Context, lw *LanguageWord, partName string, see TranslationTable, options WiktionaryOptions) ([]TranslationTable, error) {
+	// fetch the page a table points to, e.g. "house/translations" or "audience#Noun", and read the
+	// tables in its Translations section for the same part of speech - the tables found there
+	// record the page they came from, and aren't followed any further
+	// the first pass of batch rendering has nothing to render in these, so doesn't need them
+	if options.batch != nil && options.batch.collecting {
+		return nil, nil
+	}
+	title := see.See
+	if i := strings.Index(title, "#"); i >= 0 {
+		partName = strings.TrimSpace(title[i+1:])
+		title = title[:i]
+	}
+	// a revision pinned for the entry is of its own page, not of the page followed
+	options.RevisionId = 0
+	// a page which doesn't exist, or has no entry for the language, just has no translations
+	page, err := getPageWikitext(ctx, title, "", options)
+	if errors.Is(err, ErrPageNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	sections, err := extractLanguageSections(getWordFromTitle(page.Title), lw.LanguageCode, processWikitext(page.Wikitext))
+	if errors.Is(err, ErrLanguageNotOnPage) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// find the Translations sections, by the part of speech each is nested under
+	var found, all []TranslationTable
+	var walk func(nodes []*sectionNode, parent string)
+	walk = func(nodes []*sectionNode, parent string) {
+		for _, node := range nodes {
+			header := strings.Trim(node.header, "=")
+			if header == "Translations" {
+				tables := readTranslationTables(node.lines, lw.Word, options)
+				all = append(all, tables...)
+				if parent == partName {
+					found = append(found, tables...)
+				}
+			}
+			walk(node.children, header)
+		}
+	}
+	walk(buildSectionTree(sections).children, "")
+	if found == nil {
+		found = all
+	}
+
+	// for a single sense, just the table with the same gloss if there is one
+	if see.Gloss != "" {
+		for _, table := range found {
+			if strings.EqualFold(table.Gloss, see.Gloss) {
+				found = []TranslationTable{table}
+				break
+			}
+		}
+	}
+
+	var tables []TranslationTable
+	for _, table := range found {
+		if table.See != "" {
+			continue
+		}
+		table.Source = page.Title
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func parseTranslationLine(nodes []Node) []TranslatedWord {
+	// read the translations on a line such as "* German: {{t+|de|rot}}, {{q|dated}} {{t|de|rotfarben}}"
+	var words []TranslatedWord
+	var quals []string     // qualifiers waiting for the next translation
+	afterSeparator := true // whether a qualifier now belongs to the next translation rather than the last one
+
+	for _, node := range nodes {
+		if node.Type == Node_Text {
+			if strings.ContainsAny(node.Text, ",;/") {
+				afterSeparator = true
+			}
+			continue
+		}
+		if node.Type != Node_Template {
+			continue
+		}
+		args := node.ArgMap()
+		switch {
+		case translationTemplates[node.Text]:
+			tw := TranslatedWord{
+				Language:        strings.TrimSpace(args["1"]),
+				Word:            strings.TrimSpace(args["2"]),
+				Transliteration: strings.TrimSpace(args["tr"]),
+				Alt:             strings.TrimSpace(args["alt"]),
+				Script:          strings.TrimSpace(args["sc"]),
+				Literal:         getPlainText(args["lit"]),
+				Genders:         getPositionalArgs(args, 3), // the genders follow the word, or are given as g=, g2= etc.
+				Qualifiers:      quals,
+			}
+			for _, key := range []string{"g", "g2", "g3"} {
+				if g := strings.TrimSpace(args[key]); g != "" {
+					tw.Genders = append(tw.Genders, g)
+				}
+			}
+			quals = nil
+			afterSeparator = false
+			if tw.Word != "" {
+				words = append(words, tw)
+			}
+		case node.Text == "t-needed":
+			words = append(words, TranslatedWord{Language: strings.TrimSpace(args["1"]), Needed: true})
+		case node.Text == "trans-see":
+			words = append(words, TranslatedWord{See: firstNonEmpty(args["2"], args["1"])})
+		case node.Text == "q" || node.Text == "qual" || node.Text == "qualifier" || node.Text == "i" || node.Text == "qf":
+			qualifiers := getPositionalArgs(args, 1)
+			// a qualifier straight after a translation is for that translation, e.g. "{{t|de|x}} {{q|dated}}"
+			if len(words) > 0 && !afterSeparator {
+				last := &words[len(words)-1]
+				last.Qualifiers = append(last.Qualifiers, qualifiers...)
+			} else {
+				quals = append(quals, qualifiers...)
+			}
+		}
+	}
+	return words
+}
+
+func findSenseId(senses []Sense, gloss string) string {
+	// the ID of the only sense whose gloss contains the gloss of a translation table, if it has one
+	gloss = strings.ToLower(strings.TrimSpace(gloss))
+	if gloss == "" {
+		return ""
+	}
+	id := ""
+	matches := 0
+	for _, sense := range senses {
+		if strings.Contains(strings.ToLower(sense.Gloss), gloss) {
+			id = sense.Id
+			matches++
+		}
+	}
+	if matches != 1 {
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,332 @@
+package wiktionary
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTranslationTables(t *testing.T) {
+	wikitext := `==English==
+
+===Etymology===
+From Old English hūs.
+
+===Noun===
+{{en-noun}}
+
+# A building for living in.{{senseid|en|building}}
+# A family line.
+
+====Translations====
+{{trans-top|building for living in}}
+* French: {{t+|fr|maison|f}}
+* German: {{t+|de|Haus|n}}, {{q|dated}} {{t|de|Heim|g=n|lit=home}}
+* Russian: {{t+|ru|дом|m|tr=dom}}
+* Welsh: {{t-needed|cy}}
+{{trans-bottom}}
+
+{{trans-top|family line}}
+* French: {{t|fr|maison|f}} {{q|royal}}
+{{trans-bottom}}
+
+{{trans-see|theatre audience|audience}}
+`
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.RequiredLanguages = []string{"fr", "de", "ru", "cy"}
+	options.Source = MapSource{"house": wikitext}
+	lw, err := (&Client{}).GetWordWithOptions("house", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	part := lw.Etymologies[0].Parts[0]
+
+	expected := []TranslationTable{
+		{
+			Gloss:   "building for living in",
+			SenseId: "building",
+			Translations: []TranslatedWord{
+				{Language: "fr", Word: "maison", Genders: []string{"f"}},
+				{Language: "de", Word: "Haus", Genders: []string{"n"}},
+				{Language: "de", Word: "Heim", Genders: []string{"n"}, Qualifiers: []string{"dated"}, Literal: "home"},
+				{Language: "ru", Word: "дом", Transliteration: "dom", Genders: []string{"m"}},
+				{Language: "cy", Needed: true},
+			},
+		},
+		{
+			Gloss:        "family line",
+			Translations: []TranslatedWord{{Language: "fr", Word: "maison", Genders: []string{"f"}, Qualifiers: []string{"royal"}}},
+		},
+		{Gloss: "theatre audience", See: "audience"},
+	}
+	if !reflect.DeepEqual(part.TranslationTables, expected) {
+		t.Fatalf(`GetWordWithOptions: expected translation tables %+v, got %+v`, expected, part.TranslationTables)
+	}
+
+	// the first table is still there on its own, for the principal meaning of the word, but
+	// without the marker for the missing Welsh translation
+	if !reflect.DeepEqual(part.Translations, expected[0].Translations[:4]) {
+		t.Fatalf(`GetWordWithOptions: expected the first table as the translations, got %+v`, part.Translations)
+	}
+}
+
+func TestGetTranslationsMarkers(t *testing.T) {
+	// GetTranslations only returns translations, not the markers for missing ones
+	wikitext := `==English==
+
+===Etymology===
+From Old English hūs.
+
+===Noun===
+{{en-noun}}
+
+# A building for living in.
+
+====Translations====
+{{trans-top|building for living in}}
+* Welsh: {{t-needed|cy}}
+* French: {{t+|fr|maison|f}}
+* Italian: {{trans-see|home}}
+{{trans-bottom}}
+`
+	server, _ := newTestApi(t, map[string]string{"house": wikitext})
+	tr, err := (&Client{BaseUrl: server.URL}).GetTranslations("house", "en", AllLanguages)
+	if err != nil {
+		t.Fatalf(`Error from GetTranslations: %q`, err)
+	}
+	expected := []TranslatedWord{{Language: "fr", Word: "maison", Genders: []string{"f"}}}
+	if !reflect.DeepEqual(tr, expected) {
+		t.Fatalf(`GetTranslations: expected %+v, got %+v`, expected, tr)
+	}
+}
+
+func TestGetTranslationsByName(t *testing.T) {
+	// the languages of the translations can be given by their names
+	wikitext := `==English==
+
+===Noun===
+{{en-noun}}
+
+# A building for living in.
+
+====Translations====
+{{trans-top|building for living in}}
+* French: {{t+|fr|maison|f}}
+* German: {{t+|de|Haus|n}}
+{{trans-bottom}}
+`
+	server, _ := newTestApi(t, map[string]string{"house": wikitext})
+	client := &Client{BaseUrl: server.URL}
+	tr, err := client.GetTranslations("house", "en", []string{"French"})
+	if err != nil {
+		t.Fatalf(`Error from GetTranslations: %q`, err)
+	}
+	expected := []TranslatedWord{{Language: "fr", Word: "maison", Genders: []string{"f"}}}
+	if !reflect.DeepEqual(tr, expected) {
+		t.Fatalf(`GetTranslations: expected %+v, got %+v`, expected, tr)
+	}
+
+	// and a name which isn't a language is an error, rather than no translations
+	_, err = client.GetTranslations("house", "en", []string{"Frnch"})
+	var langErr *UnknownLanguageCodeError
+	if !errors.As(err, &langErr) || len(langErr.Suggestions) == 0 || langErr.Suggestions[0] != "fr" {
+		t.Fatalf(`GetTranslations: expected an UnknownLanguageCodeError suggesting "fr", got %q`, err)
+	}
+}
+
+func TestFollowTranslations(t *testing.T) {
+	wikitext := `==English==
+
+===Etymology===
+From Old English wæter.
+
+===Noun===
+{{en-noun}}
+
+# A clear liquid.{{senseid|en|liquid}}
+
+====Translations====
+{{see translation subpage|Noun}}
+
+===Verb===
+{{en-verb}}
+
+# To pour water on.
+
+====Translations====
+{{trans-see|to pour water on|irrigate}}
+`
+	subpage := `==English==
+
+===Noun===
+====Translations====
+{{trans-top|clear liquid|id=liquid}}
+* French: {{t+|fr|eau|f}}
+{{trans-bottom}}
+
+===Verb===
+====Translations====
+{{trans-top|to pour water on}}
+* French: {{t+|fr|arroser}}
+{{trans-bottom}}
+`
+	irrigate := `==English==
+
+===Verb===
+{{en-verb}}
+
+# To supply with water.
+
+====Translations====
+{{trans-top|to supply with water}}
+* French: {{t+|fr|irriguer}}
+{{trans-bottom}}
+
+{{trans-top|to clean a wound}}
+* French: {{t+|fr|irriguer}}
+{{trans-bottom}}
+`
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.RequiredLanguages = AllLanguages
+	options.Source = MapSource{"water": wikitext, "water/translations": subpage, "irrigate": irrigate}
+	lw, err := (&Client{}).GetWordWithOptions("water", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+
+	// the subpage has the tables for the noun, but not those for the verb
+	noun := lw.Etymologies[0].Parts[0]
+	expected := []TranslationTable{
+		{See: "water/translations#Noun"},
+		{
+			Gloss:        "clear liquid",
+			SenseId:      "liquid",
+			Translations: []TranslatedWord{{Language: "fr", Word: "eau", Genders: []string{"f"}}},
+			Source:       "water/translations",
+		},
+	}
+	if !reflect.DeepEqual(noun.TranslationTables, expected) {
+		t.Fatalf(`GetWordWithOptions: expected the noun's tables %+v, got %+v`, expected, noun.TranslationTables)
+	}
+	if len(noun.Translations) != 1 || noun.Translations[0].Word != "eau" {
+		t.Fatalf(`GetWordWithOptions: expected the subpage's first table as the translations, got %+v`, noun.Translations)
+	}
+
+	// with no table of the same gloss, every table for the same part of speech is taken
+	verb := lw.Etymologies[0].Parts[1]
+	if len(verb.TranslationTables) != 3 || verb.TranslationTables[0].See != "irrigate" {
+		t.Fatalf(`GetWordWithOptions: expected the trans-see and the two tables it points to, got %+v`, verb.TranslationTables)
+	}
+	for _, table := range verb.TranslationTables[1:] {
+		if table.Source != "irrigate" || len(table.Translations) != 1 || table.Translations[0].Word != "irriguer" {
+			t.Fatalf(`GetWordWithOptions: expected a table from irrigate, got %+v`, table)
+		}
+	}
+}
+
+func TestFollowTranslationsPinned(t *testing.T) {
+	// a pinned revision is of the entry's own page, so the subpage is fetched as it is now
+	wikitext := `==English==
+
+===Etymology===
+From Old English wæter.
+
+===Noun===
+{{en-noun}}
+
+# A clear liquid.
+
+====Translations====
+{{see translation subpage|Noun}}
+`
+	subpage := `==English==
+
+===Noun===
+====Translations====
+{{trans-top|clear liquid}}
+* French: {{t+|fr|eau|f}}
+{{trans-bottom}}
+`
+	server, requests := newTestApi(t, map[string]string{"water@90": wikitext, "water/translations": subpage})
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_Parts | Sec_Translations
+	options.RequiredLanguages = AllLanguages
+	options.RevisionId = 90
+	lw, err := (&Client{BaseUrl: server.URL}).GetWordWithOptions("water", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+
+	noun := lw.Etymologies[0].Parts[0]
+	if len(noun.Translations) != 1 || noun.Translations[0].Word != "eau" {
+		t.Fatalf(`GetWordWithOptions: expected the translation from the subpage, got %+v`, noun.Translations)
+	}
+	if table := noun.TranslationTables[1]; table.Source != "water/translations" {
+		t.Fatalf(`GetWordWithOptions: expected the table to come from the subpage, got %q`, table.Source)
+	}
+	for _, r := range *requests {
+		if r.Form.Get("titles") == "water/translations" && r.Form.Get("revids") != "" {
+			t.Fatalf(`GetWordWithOptions: expected the subpage to be fetched without a revision, got %q`, r.Form.Get("revids"))
+		}
+	}
+}
+
+func TestFollowTranslationsError(t *testing.T) {
+	// a subpage which can't be read is reported, rather than taken to have no translations
+	wikitext := `==English==
+
+===Noun===
+{{en-noun}}
+
+# A clear liquid.
+
+====Translations====
+{{see translation subpage|Noun}}
+`
+	api, _ := newTestApi(t, map[string]string{"water": wikitext})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("titles") == "water/translations" {
+			w.Write([]byte(`{"error":{"code":"internal_api_error_DBQueryError","info":"Database query error."}}`))
+			return
+		}
+		resp, err := http.PostForm(api.URL, r.Form)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		io.Copy(w, resp.Body)
+	}))
+	defer server.Close()
+	client := &Client{BaseUrl: server.URL}
+
+	tr, err := client.GetTranslations("water", "en", AllLanguages)
+	var apiErr *APIError
+	if len(tr) != 0 || !errors.As(err, &apiErr) || apiErr.Code != "internal_api_error_DBQueryError" {
+		t.Fatalf(`GetTranslations: expected the error fetching the subpage, got %+v, %q`, tr, err)
+	}
+
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_Parts | Sec_Translations
+	options.RequiredLanguages = AllLanguages
+	lw, err := client.GetWordWithOptions("water", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	tables := lw.Etymologies[0].Parts[0].TranslationTables
+	if len(tables) != 1 || tables[0].See != "water/translations#Noun" || tables[0].Error != apiErr.Error() {
+		t.Fatalf(`GetWordWithOptions: expected the error on the table pointing to the subpage, got %+v`, tables)
+	}
+
+	// but a subpage which doesn't exist just has no translations
+	tr, err = (&Client{BaseUrl: api.URL}).GetTranslations("water", "en", AllLanguages)
+	if len(tr) != 0 || err != nil {
+		t.Fatalf(`GetTranslations: expected no translations and no error, got %+v, %q`, tr, err)
+	}
+}
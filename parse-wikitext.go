@@ -1,12 +1,17 @@
 package wiktionary
 
 import (
-	"fmt"
+	"context"
 	"regexp"
 	"strings"
 )
 
-func parseSections(word string, langCode string, sections []Section, options WiktionaryOptions) LanguageWord {
+func parseSections(ctx context.Context, word string, langCode string, sections []Section, options WiktionaryOptions) LanguageWord {
+	// if we are rendering in batches, render everything the page needs up front
+	if options.BatchRendering && options.batch == nil {
+		options.batch = prepareRenderBatch(ctx, word, langCode, sections, options)
+	}
+
 	// define the LanguageWord
 	lw := LanguageWord{
 		Word:         word,
@@ -14,10 +19,10 @@ func parseSections(word string, langCode string, sections []Section, options Wik
 		LanguageName: getLanguageFromCode(langCode),
 	}
 
-	// iterate over the sections - ignore the first as it's the language header
-	for i := 1; i < len(sections); i++ {
-		parseSection(&lw, sections[i], options)
-	}
+	// walk the sections under the language header, so that each one is attached to the
+	// etymology or part of speech it is nested under
+	tree := buildSectionTree(sections)
+	parseSectionTree(ctx, &lw, tree.children, languageScope, options)
 
 	// assign a meaning - take the first entry in the first part of the first etymology
 	if len(lw.Etymologies) > 0 {
@@ -31,80 +36,106 @@ func parseSections(word string, langCode string, sections []Section, options Wik
 	return lw
 }
 
-func parseSection(lw *LanguageWord, section Section, options WiktionaryOptions) {
+func parseSectionTree(ctx context.Context, lw *LanguageWord, nodes []*sectionNode, scope sectionScope, options WiktionaryOptions) {
+	// parse each section, then the sections nested under it
+	for _, node := range nodes {
+		childScope := parseSection(ctx, lw, node.Section, scope, options)
+		parseSectionTree(ctx, lw, node.children, childScope, options)
+	}
+}
+
+func parseSection(ctx context.Context, lw *LanguageWord, section Section, scope sectionScope, options WiktionaryOptions) sectionScope {
 	// determine the section type
 	sectionType := strings.Trim(section.header, "=")
 
-	// process each type separately
+	// process each type separately, returning the scope of the sections nested under this one
 	// etymology requires special handling as it may have numbers after it
 	if strings.HasPrefix(sectionType, "Etymology") {
-		parseEtymologySection(lw, section, options)
+		return parseEtymologySection(ctx, lw, section, options)
 	} else {
 
 		// process others
 		switch sectionType {
 		case "Pronunciation":
 			if sectionRequired(options, Sec_IPA) || sectionRequired(options, Sec_Extended_Pronunciation) {
-				parsePronunciationSection(lw, section, options)
+				parsePronunciationSection(ctx, lw, section, scope, options)
 			}
 		case "Noun", "Verb", "Adjective", "Adverb", "Article", "Ambiposition", "Circumposition", "Classifier",
 			"Conjunction", "Contraction", "Counter", "Determiner", "Ideophone", "Interjection", "Numeral",
 			"Participle", "Particle", "Postposition", "Preposition", "Proper noun", "Circumfix", "Combining form",
 			"Infix", "Interfix", "Prefix", "Root", "Suffix", "Phrase", "Proverb", "Prepositional phrase":
 			if sectionRequired(options, Sec_Parts) {
-				parsePartofSpeechSection(lw, section, options)
+				return parsePartofSpeechSection(ctx, lw, section, scope, options)
 			}
 		case "Declension", "Conjugation":
 			if sectionRequired(options, Sec_Part_Extended) {
-				parseExtendedPartSection(lw, section, options)
+				parseExtendedPartSection(ctx, lw, section, scope, options)
 			}
 		case "Translations":
 			if sectionRequired(options, Sec_Translations) {
-				parseTranslationSection(lw, section, options)
+				parseTranslationSection(ctx, lw, section, scope, options)
 			}
 		case "Descendants":
 			if sectionRequired(options, Sec_Etymology_Words) {
-				parseDescendantSection(lw, section, options)
+				parseDescendantSection(ctx, lw, section, scope, options)
 			}
 		case "Synonyms", "Antonyms", "Anagrams", "Alternative forms":
 			if sectionRequired(options, Sec_Synonyms) || sectionRequired(options, Sec_Antonyms) {
-				parseOtherSections(lw, section, options)
+				parseOtherSections(ctx, lw, section, scope, options)
+			}
+			if sectionRequired(options, Sec_Relations) && relationSections[sectionType] != "" {
+				parseRelationSection(ctx, lw, section, scope, options)
+			}
+		case "Derived terms", "Related terms", "Hyponyms", "Hypernyms", "Meronyms", "Holonyms", "Troponyms", "Coordinate terms":
+			if sectionRequired(options, Sec_Relations) {
+				parseRelationSection(ctx, lw, section, scope, options)
 			}
 		default:
-			// ignore all others
+			// keep all others as they are, on the entry they are nested under
+			if sectionRequired(options, Sec_Other_Sections) {
+				parseUnhandledSection(ctx, lw, section, scope, options)
+			}
 		}
 
 	}
+	return scope
 }
 
-func parsePronunciationSection(lw *LanguageWord, section Section, options WiktionaryOptions) {
+func parsePronunciationSection(ctx context.Context, lw *LanguageWord, section Section, scope sectionScope, options WiktionaryOptions) {
 	var pr []string
 	var ipa string
 	var text string
+	pron := new(Pronunciation)
 	// read each line - it should begin with a * - into the slice
 	for _, line := range section.lines {
 		if strings.HasPrefix(line, "*") {
+			// read the templates on the line into the pronunciation
+			parsePronunciationLine(line, pron)
+
 			// process the pronunciation line
-			// special handling for the audio line
-			prTag := searchForTag(line, "audio")
-			if prTag != "" {
-				elems := splitTag(prTag)
-				if val, ok := elems["2"]; ok {
-					pr = append(pr, "Audio: https://en.wiktionary.org/wiki/File:"+val)
+			// special handling for the audio line, which links to the audio file itself
+			if audio := findTemplate(ParseWikitext(line), "audio"); audio != nil {
+				if val, ok := audio.Arg("2"); ok {
+					pr = append(pr, "Audio: "+MediaUrl(val))
 				}
 				continue
 			}
 
-			text, _ = getConvertedTextFromWiktionary(line[2:], lw.Word, lw.LanguageCode)
+			text, _ = getConvertedTextFromWiktionary(ctx, line[2:], lw.Word, lw.LanguageCode, options)
 			if sectionRequired(options, Sec_Extended_Pronunciation) {
 				if text != "" {
 					pr = append(pr, text)
 				}
 			}
-			// find the first occurence of an IPA tag and record that separately
-			// NB some languages have an automatically generated IPA - for simplicity
-			// we will always use the text version rther than the tag
+			// find the first occurence of an IPA transcription and record that separately - from
+			// the template if there is one, otherwise from the text, as some languages have an
+			// automatically generated IPA, e.g. {{fr-IPA}}
 			if sectionRequired(options, Sec_IPA) {
+				for _, tr := range pron.Transcriptions {
+					if ipa == "" && len(tr.Phonemic) > 0 {
+						ipa = tr.Phonemic[0]
+					}
+				}
 				if ipa == "" {
 					re := regexp.MustCompile(`.*(\/.*?\/)`)
 					match := re.FindStringSubmatch(text)
@@ -115,21 +146,26 @@ func parsePronunciationSection(lw *LanguageWord, section Section, options Wiktio
 			}
 		}
 	}
+	if len(pron.Transcriptions) == 0 && len(pron.Rhymes) == 0 && len(pron.Homophones) == 0 &&
+		len(pron.Hyphenation) == 0 && len(pron.Audio) == 0 {
+		pron = nil
+	}
 
 	// this section is usually added to the main language word
 	// however if there are homographs (words spelled the same but pronounced differently)
-	// then the pronunciation will be tied to the etymology
-	// the way to tell is to see if there are any etymologies added yet - if so add to the latest one
-	if len(lw.Etymologies) == 0 {
+	// then the pronunciation will be nested under the etymology it belongs to
+	if scope.etym < 0 {
 		lw.Pronunciations = pr
 		lw.Ipa = ipa
+		lw.Pronunciation = pron
 	} else {
-		lw.Etymologies[len(lw.Etymologies)-1].Pronunciations = pr
-		lw.Etymologies[len(lw.Etymologies)-1].Ipa = ipa
+		lw.Etymologies[scope.etym].Pronunciations = pr
+		lw.Etymologies[scope.etym].Ipa = ipa
+		lw.Etymologies[scope.etym].Pronunciation = pron
 	}
 }
 
-func parseEtymologySection(lw *LanguageWord, section Section, options WiktionaryOptions) {
+func parseEtymologySection(ctx context.Context, lw *LanguageWord, section Section, options WiktionaryOptions) sectionScope {
 	var etym Etymology
 	etym.Name = strings.Trim(section.header, "=")
 	lw.Etymologies = append(lw.Etymologies, etym)
@@ -141,7 +177,7 @@ func parseEtymologySection(lw *LanguageWord, section Section, options Wiktionary
 
 		// get the etymology text
 		if sectionRequired(options, Sec_Etymology_Text) || sectionRequired(options, Sec_Etymology_Words) {
-			text, _ := getConvertedTextFromWiktionary(line, lw.Word, lw.LanguageCode)
+			text, _ := getConvertedTextFromWiktionary(ctx, line, lw.Word, lw.LanguageCode, options)
 			if sectionRequired(options, Sec_Etymology_Text) {
 				lw.Etymologies[currentEtym].Text += text
 			}
@@ -151,83 +187,83 @@ func parseEtymologySection(lw *LanguageWord, section Section, options Wiktionary
 
 			// get the word link tags from the etymology
 			if sectionRequired(options, Sec_Etymology_Words) {
-				parseLinkedWord(lw, line, text, options)
+				parseLinkedWord(&lw.Etymologies[currentEtym], line, text, options)
 			}
 		}
 	}
 
+	// the sections nested under this one belong to this etymology
+	return sectionScope{etym: currentEtym, part: -1}
 }
 
-func parseLinkedWord(lw *LanguageWord, line string, text string, options WiktionaryOptions) {
-	currentEtym := len(lw.Etymologies) - 1
+func parseLinkedWord(etym *Etymology, line string, text string, options WiktionaryOptions) {
+	// there must be an etymology to add the words to
+	if etym == nil {
+		return
+	}
 
-	tags := getAllTags(line)
-	for _, tag := range tags {
+	for _, tmpl := range Templates(ParseWikitext(line)) {
 		var link LinkedWord
 		link.Attributes = make(map[string]bool)
 
-		elems := splitTag(tag[1])
+		elems := templateArgs(tmpl)
+		relationship, ok := getRelationship(elems["0"])
 		// ignore the m tag, it's sometimes used in etymologies, and it's ambiguous
-		if elems["0"] == "m" || elems["0"] == "mention" {
+		if !ok || relationship == Mention {
 			continue
 		}
+		link.Relationship = getFlaggedRelationship(relationship, elems)
 
 		// depending on the tag type, process accordingly
-		switch elems["0"] {
-		case "root":
-			link.Relationship = Root
+		switch relationship {
+		case Root:
 			if val, ok := elems["3"]; ok {
 				link.Word = val
 			}
 			if val, ok := elems["2"]; ok {
 				link.Language = val
 			}
-		case "inh", "inherited", "bor", "borrowed", "lbor", "learned borrowing",
-			"sl", "semantic loan", "der", "derived",
-			"cal", "clq", "calque", "pcal", "pclq", "partial calque":
-			link.Relationship = Inherited
-			if val, ok := elems["3"]; ok {
+		case Cognate:
+			if val, ok := elems["2"]; ok {
 				link.Word = val
 			}
-			if val, ok := elems["2"]; ok {
+			if val, ok := elems["1"]; ok {
 				link.Language = val
 			}
-			if val, ok := elems["5"]; ok {
+			if val, ok := elems["4"]; ok {
 				link.Meaning = val
 			}
 			// the meaning should appear at slot 4, but sometimes it's at slot 5
 			// this is non-standard, but happens
-			if val, ok := elems["6"]; ok && link.Meaning == "" {
+			if val, ok := elems["5"]; ok && link.Meaning == "" {
 				link.Meaning = val
 			}
-		case "cog", "cognate":
-			link.Relationship = Cognate
+		case Descendant:
+			if val, ok := elems["1"]; ok {
+				link.Language = val
+			}
 			if val, ok := elems["2"]; ok {
 				link.Word = val
 			}
-			if val, ok := elems["1"]; ok {
+		default:
+			// inherited, borrowed, derived, calques and the like all have the same arguments
+			if val, ok := elems["3"]; ok {
+				link.Word = val
+			}
+			if val, ok := elems["2"]; ok {
 				link.Language = val
 			}
-			if val, ok := elems["4"]; ok {
+			if val, ok := elems["5"]; ok {
 				link.Meaning = val
 			}
 			// the meaning should appear at slot 4, but sometimes it's at slot 5
 			// this is non-standard, but happens
-			if val, ok := elems["5"]; ok && link.Meaning == "" {
+			if val, ok := elems["6"]; ok && link.Meaning == "" {
 				link.Meaning = val
 			}
-		case "desc", "descendant", "desctree":
-			link.Relationship = Descendant
-			if val, ok := elems["1"]; ok {
-				link.Language = val
-			}
-			if val, ok := elems["2"]; ok {
-				link.Word = val
-			}
-
 		}
 
-		// handle non-standard inheritance
+		// the attributes older callers use to tell the relationships apart
 		if elems["0"] == "borrowed" || elems["0"] == "bor" ||
 			elems["0"] == "learned borrowing" || elems["0"] == "lbor" {
 			link.Attributes["borrowed"] = true
@@ -284,24 +320,24 @@ func parseLinkedWord(lw *LanguageWord, line string, text string, options Wiktion
 
 		// if the target word exists, save it
 		if link.Word != "" && link.Word != "-" {
-			lw.Etymologies[currentEtym].Words = append(lw.Etymologies[currentEtym].Words, link)
+			etym.Words = append(etym.Words, link)
 		}
 	}
 
 }
 
-func parseDescendantSection(lw *LanguageWord, section Section, options WiktionaryOptions) {
-	// read in all descendant words and add them to LinkedWords in the current Etymology
+func parseDescendantSection(ctx context.Context, lw *LanguageWord, section Section, scope sectionScope, options WiktionaryOptions) {
+	// read in all descendant words and add them to LinkedWords in the Etymology the section is under
 	for _, line := range section.lines {
 		if strings.HasPrefix(line, "*") {
-			text, _ := getConvertedTextFromWiktionary(line, lw.Word, lw.LanguageCode)
-			parseLinkedWord(lw, line, text, options)
+			text, _ := getConvertedTextFromWiktionary(ctx, line, lw.Word, lw.LanguageCode, options)
+			parseLinkedWord(scope.etymology(lw), line, text, options)
 		}
 	}
 
 }
 
-func parsePartofSpeechSection(lw *LanguageWord, section Section, options WiktionaryOptions) {
+func parsePartofSpeechSection(ctx context.Context, lw *LanguageWord, section Section, scope sectionScope, options WiktionaryOptions) sectionScope {
 	var pos PartOfSpeech
 	pos.Attributes = make(map[string]string)
 	pos.Name = strings.Trim(section.header, "=")
@@ -311,16 +347,18 @@ func parsePartofSpeechSection(lw *LanguageWord, section Section, options Wiktion
 	for _, line := range section.lines {
 		// the headword line will have tags
 		if strings.HasPrefix(line, "{{") && pos.Headword == "" {
-			text, _ := getConvertedTextFromWiktionary(line, lw.Word, lw.LanguageCode)
+			text, _ := getConvertedTextFromWiktionary(ctx, line, lw.Word, lw.LanguageCode, options)
 			headTag = line
 			pos.Headword = text
 		}
-		// find meaning lines (but not quotations - maybe later)
-		if sectionRequired(options, Sec_Meanings) {
-			if strings.HasPrefix(line, "# ") {
-				text, _ := getConvertedTextFromWiktionary(line[2:], lw.Word, lw.LanguageCode)
-				pos.Meanings = append(pos.Meanings, text)
-			}
+	}
+
+	// read the senses, with their examples, quotations and sub-senses - the top-level ones are
+	// also kept as a flat list of meanings
+	if sectionRequired(options, Sec_Meanings) {
+		pos.Senses = parseSenses(ctx, lw, section.lines, options)
+		for _, sense := range pos.Senses {
+			pos.Meanings = append(pos.Meanings, sense.text)
 		}
 	}
 
@@ -338,18 +376,25 @@ func parsePartofSpeechSection(lw *LanguageWord, section Section, options Wiktion
 		}
 	}
 
-	if len(lw.Etymologies) > 0 {
-		lw.Etymologies[len(lw.Etymologies)-1].Parts = append(lw.Etymologies[len(lw.Etymologies)-1].Parts, pos)
-	} else { // if there is no etymology section yet (as may happen with a root) then create a default one
+	// add the part to the etymology it is nested under, or on a page with a single etymology
+	// to that etymology
+	if scope.etymology(lw) == nil { // if there is no etymology section yet (as may happen with a root) then create a default one
 		var etym Etymology
 		etym.Name = "Inferred Etymology"
 		lw.Etymologies = append(lw.Etymologies, etym)
-		lw.Etymologies[0].Parts = append(lw.Etymologies[0].Parts, pos)
 	}
+	if scope.etym < 0 {
+		scope.etym = len(lw.Etymologies) - 1
+	}
+	lw.Etymologies[scope.etym].Parts = append(lw.Etymologies[scope.etym].Parts, pos)
+
+	// the sections nested under this one belong to this part of speech
+	scope.part = len(lw.Etymologies[scope.etym].Parts) - 1
+	return scope
 }
 
 func parseNoun(pos *PartOfSpeech, headTag string) {
-	tagMap := splitTag(headTag)
+	tagMap := templateArgs(findTemplate(ParseWikitext(headTag)))
 	gendered := false
 
 	// NB gender, if it exists, will be param 1 - it will be one of m f n c m-p f-p n-p c-p mf p
@@ -445,7 +490,7 @@ func parseAdjective(pos *PartOfSpeech, headTag string) {
 }
 
 func parseVerb(pos *PartOfSpeech, headTag string) {
-	tagMap := splitTag(headTag)
+	tagMap := templateArgs(findTemplate(ParseWikitext(headTag)))
 	// get the headword forms from the text
 	sppp := getHeadwordForm(pos, "simple past and past participle")
 	// if we have this specific combined form, don't check for separate forms
@@ -481,14 +526,10 @@ func parseVerb(pos *PartOfSpeech, headTag string) {
 
 }
 
-func parseExtendedPartSection(lw *LanguageWord, section Section, options WiktionaryOptions) {
+func parseExtendedPartSection(ctx context.Context, lw *LanguageWord, section Section, scope sectionScope, options WiktionaryOptions) {
 	// there must be an existing part of speech in an existing etymology
-	currentEtym := len(lw.Etymologies) - 1
-	if currentEtym < 0 {
-		return
-	}
-	currentPart := len(lw.Etymologies[currentEtym].Parts) - 1
-	if currentPart < 0 {
+	part := scope.partOfSpeech(lw)
+	if part == nil {
 		return
 	}
 
@@ -496,8 +537,8 @@ func parseExtendedPartSection(lw *LanguageWord, section Section, options Wiktion
 	for _, line := range section.lines {
 		// the headword line will have tags
 		if strings.HasPrefix(line, "{{") {
-			text, _ := getTableFromWiktionary(line, lw.Word, lw.LanguageCode)
-			parseInflectionTable(&lw.Etymologies[currentEtym].Parts[currentPart], text)
+			text, _ := getTableFromWiktionary(ctx, line, lw.Word, lw.LanguageCode, options)
+			parseInflectionTable(part, text)
 		}
 	}
 }
@@ -531,52 +572,7 @@ func getHeadwordItem(pos *PartOfSpeech, form string, index int) bool {
 	return false
 }
 
-func parseTranslationSection(lw *LanguageWord, section Section, options WiktionaryOptions) {
-	var tr []TranslatedWord
-
-	// NB we will only record the first translation block as this will be the principal meaning
-	// many words hav translations of colloquial meanings which are not relevant
-	for _, line := range section.lines {
-		// read lines on until we reach the first {{trans-bottom}} tag, then exit
-		if strings.HasPrefix(line, "{{trans-bottom") {
-			break
-		}
-		if strings.HasPrefix(line, "*") {
-			tags := getAllTags(line)
-			for _, tag := range tags {
-				elems := splitTag(tag[1])
-				// if we have a translation tag
-				if elems["0"] == "t" || elems["0"] == "t+" || elems["0"] == "tt" || elems["0"] == "tt+" {
-					var tw TranslatedWord
-					if val, ok := elems["1"]; ok {
-						tw.Language = val
-					}
-					if val, ok := elems["2"]; ok {
-						tw.Word = val
-						if val, ok := elems["tr"]; ok {
-							tw.Transliteration = val
-						}
-						// if the language is required
-						if languageRequired(options, tw.Language) {
-							tr = append(tr, tw)
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// add this section to the current part in the current etymology
-	if len(lw.Etymologies) > 0 && len(tr) > 0 {
-		currentEtym := len(lw.Etymologies) - 1
-		if len(lw.Etymologies[currentEtym].Parts) > 0 {
-			currentPart := len(lw.Etymologies[len(lw.Etymologies)-1].Parts) - 1
-			lw.Etymologies[currentEtym].Parts[currentPart].Translations = tr
-		}
-	}
-}
-
-func parseOtherSections(lw *LanguageWord, section Section, options WiktionaryOptions) {
+func parseOtherSections(ctx context.Context, lw *LanguageWord, section Section, scope sectionScope, options WiktionaryOptions) {
 	// used for synonyms, antonyms and other sections where we just return the text
 	var secText string
 	header := strings.Trim(section.header, "=")
@@ -586,7 +582,7 @@ func parseOtherSections(lw *LanguageWord, section Section, options WiktionaryOpt
 				(sectionRequired(options, Sec_Antonyms) && header == "Antonyms") ||
 				(sectionRequired(options, Sec_Anagrams) && header == "Anagrams") ||
 				(sectionRequired(options, Sec_Alternatives) && header == "Alternative forms") {
-				text, _ := getConvertedTextFromWiktionary(line[2:], lw.Word, lw.LanguageCode)
+				text, _ := getConvertedTextFromWiktionary(ctx, line[2:], lw.Word, lw.LanguageCode, options)
 				if text != "" {
 					secText += text + "\n"
 				}
@@ -603,63 +599,84 @@ func parseOtherSections(lw *LanguageWord, section Section, options WiktionaryOpt
 	}
 
 	// sections that live at the Etymology level
-	currentEtym := len(lw.Etymologies) - 1
-	if currentEtym < 0 {
+	etym := scope.etymology(lw)
+	if etym == nil {
 		return
 	}
 	if header == "Alternative forms" {
 		if sectionRequired(options, Sec_Alternatives) && secText != "" {
-			lw.Etymologies[currentEtym].AlternativeForms = secText
+			etym.AlternativeForms = secText
 		}
 		return
 	}
 
 	// sections that live at the PartOfSpeech level
-	currentPart := len(lw.Etymologies[currentEtym].Parts) - 1
-	if currentPart < 0 {
+	part := scope.partOfSpeech(lw)
+	if part == nil {
 		return
 	}
 	if secText != "" {
 		switch header {
 		case "Synonyms":
-			lw.Etymologies[currentEtym].Parts[currentPart].Synonyms = secText
+			part.Synonyms = secText
 		case "Antonyms":
-			lw.Etymologies[currentEtym].Parts[currentPart].Antonyms = secText
+			part.Antonyms = secText
 		}
 	}
 }
 
-func getAllTags(text string) [][]string {
-	// return all wikitext tags in the text
-	re := regexp.MustCompile(`\{\{(.*?)\}\}`)
-	match := re.FindAllStringSubmatch(text, -1)
-	return match
-}
+func parseUnhandledSection(ctx context.Context, lw *LanguageWord, section Section, scope sectionScope, options WiktionaryOptions) {
+	// keep the wikitext of a section we have no parser for, along with its rendered text
+	other := OtherSection{
+		Name:     strings.TrimSpace(strings.Trim(section.header, "=")),
+		Wikitext: strings.Join(section.lines, "\n"),
+	}
+	var text []string
+	for _, line := range section.lines {
+		// render list items without their list markup, as the other sections do
+		rendered, _ := getConvertedTextFromWiktionary(ctx, strings.TrimLeft(line, "*#:; "), lw.Word, lw.LanguageCode, options)
+		if rendered != "" {
+			text = append(text, rendered)
+		}
+	}
+	other.Text = strings.Join(text, "\n")
 
-func searchForTag(text string, tag string) string {
-	// return a tag of form {{head|param1|param2}} if it exists in the given text, otherwise ""
-	re := regexp.MustCompile(`\{\{` + tag + `(.*?)\}\}`)
-	match := re.FindStringSubmatch(text)
-	if len(match) != 0 {
-		return match[0]
+	// add it to the part of speech or etymology it is nested under, or to the word itself
+	switch {
+	case scope.part >= 0:
+		part := &lw.Etymologies[scope.etym].Parts[scope.part]
+		part.OtherSections = append(part.OtherSections, other)
+	case scope.etym >= 0:
+		lw.Etymologies[scope.etym].OtherSections = append(lw.Etymologies[scope.etym].OtherSections, other)
+	default:
+		lw.OtherSections = append(lw.OtherSections, other)
 	}
-	return ""
 }
 
-func splitTag(tag string) map[string]string {
-	// given a tag of form {{head|param1|param2}}, return a map of the components of the tag
-	tagMap := make(map[string]string)
-	text := strings.Trim(tag, "{}")
-	tags := strings.Split(text, "|")
-	for i, elem := range tags {
-		if strings.Contains(elem, "=") {
-			keyval := strings.Split(elem, "=")
-			tagMap[keyval[0]] = keyval[1]
-		} else {
-			tagMap[fmt.Sprint(i)] = elem
+func findTemplate(nodes []Node, names ...string) *Node {
+	// return the first template in the nodes with one of the given names, or any template if
+	// no names are given - nil if there is none
+	for _, tmpl := range Templates(nodes) {
+		if len(names) == 0 {
+			return tmpl
 		}
+		for _, name := range names {
+			if tmpl.Text == name {
+				return tmpl
+			}
+		}
+	}
+	return nil
+}
+
+func templateArgs(tmpl *Node) map[string]string {
+	// return a map of the template's arguments, with its name as "0" - empty for a nil template
+	if tmpl == nil {
+		return map[string]string{}
 	}
-	return tagMap
+	args := tmpl.ArgMap()
+	args["0"] = tmpl.Text
+	return args
 }
 
 func sectionRequired(options WiktionaryOptions, section int16) bool {
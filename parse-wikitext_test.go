@@ -151,3 +151,53 @@ func TestHtmlTableProcessing(t *testing.T) {
 	pos.Headword = "lūna f (genitive lūnae); first declension"
 	testAttributes(t, &pos, "{{la-ndecl|l\u016bna<1>}}", "genitive", "lūnae", true)
 }
+
+func TestOtherSections(t *testing.T) {
+	wikitext := `==English==
+
+===Etymology 1===
+From Old English lēad.
+
+====Noun====
+{{en-noun}}
+
+# A heavy metal.
+
+=====Usage notes=====
+* Often confused with ''led''.
+
+====References====
+* [[w:Lead|Lead]] on Wikipedia
+
+===See also===
+* {{l|en|plumb}}
+`
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{"lead": wikitext}
+	lw, err := (&Client{}).GetWordWithOptions("lead", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+
+	// each section is kept on the entry it is nested under, as wikitext and as text
+	expected := OtherSection{Name: "Usage notes", Wikitext: "* Often confused with ''led''.", Text: "Often confused with led."}
+	if parts := lw.Etymologies[0].Parts; len(parts[0].OtherSections) != 1 || parts[0].OtherSections[0] != expected {
+		t.Fatalf(`GetWordWithOptions: expected %+v on the noun, got %+v`, expected, parts[0].OtherSections)
+	}
+	expected = OtherSection{Name: "References", Wikitext: "* [[w:Lead|Lead]] on Wikipedia", Text: "Lead on Wikipedia"}
+	if others := lw.Etymologies[0].OtherSections; len(others) != 1 || others[0] != expected {
+		t.Fatalf(`GetWordWithOptions: expected %+v on the etymology, got %+v`, expected, others)
+	}
+	expected = OtherSection{Name: "See also", Wikitext: "* {{l|en|plumb}}", Text: "plumb"}
+	if len(lw.OtherSections) != 1 || lw.OtherSections[0] != expected {
+		t.Fatalf(`GetWordWithOptions: expected %+v on the word, got %+v`, expected, lw.OtherSections)
+	}
+
+	// but only if they are required
+	options.RequiredSections = Sec_Core
+	lw, _ = (&Client{}).GetWordWithOptions("lead", "en", options)
+	if len(lw.OtherSections) != 0 || len(lw.Etymologies[0].OtherSections) != 0 {
+		t.Fatalf(`GetWordWithOptions: expected no other sections without Sec_Other_Sections`)
+	}
+}
@@ -1,15 +1,11 @@
 package wiktionary
 
 import (
-	"errors"
-	"fmt"
-	"io/ioutil"
-	"net/http"
+	"context"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
-	"unicode/utf16"
 )
 
 type Section struct {
@@ -17,70 +13,55 @@ type Section struct {
 	lines  []string
 }
 
-func getWordDataFromWiktionary(word string, langCode string) ([]byte, error) {
-	// for a given word, retrieve the word's JSON data from Wiktionary
-	urlHead := "https://en.wiktionary.org/w/api.php?action=parse&page="
-	urlTail := "&prop=wikitext&format=json"
-
-	// make an HHTP request to Wiktionary
-	url := urlHead + url.QueryEscape(getPageTitle(word, langCode)) + urlTail
-	resp, err := http.Get(url)
+func getWordDataFromWiktionary(ctx context.Context, source ApiSource, word string, langCode string, revisionId int64) (*PageResponse, error) {
+	// for a given word, retrieve the word's page from Wiktionary - either the latest revision,
+	// or the given one if it has been pinned
+	title := getPageTitle(word, langCode)
+	params := url.Values{}
+	params.Set("action", "query")
+	params.Set("prop", "revisions")
+	if revisionId != 0 {
+		params.Set("revids", strconv.FormatInt(revisionId, 10))
+	} else {
+		params.Set("titles", title)
+	}
+	params.Set("rvprop", "ids|timestamp|content")
+	params.Set("rvslots", "main")
+	params.Set("redirects", "1")
+	params.Set("formatversion", "2")
+	params.Set("format", "json")
+
+	// make an HTTP request to Wiktionary and decode the JSON response
+	body, err := source.call(ctx, params, false)
 	if err != nil {
 		return nil, err
 	}
-
-	// process the response to retrieve the body
-	body, err := ioutil.ReadAll(resp.Body)
+	page, err := decodePageResponse(body, word, title)
 	if err != nil {
 		return nil, err
 	}
 
-	// return the byte string
-	return body, nil
-}
-
-func getWikitext(wordData []byte, word string) (string, error) {
-	// use regex to locate the wikitext within the JSON data
-	// NB we could process the JSON itself, but as we don't need anything else, this would be inefficient
-	re := regexp.MustCompile(`\"wikitext\":\{\"\*\":\"(.*?)\"\}\}\}$`)
-	match := re.FindStringSubmatch(string(wordData))
-	if len(match) == 0 {
-		msg := fmt.Sprintf("No wikitext for word '%s'", word)
-		return "", errors.New(msg)
+	// a revision ID picks out a page by itself, so check that it's a revision of the page wanted
+	if revisionId != 0 && !isPageForTitle(page, title) {
+		return nil, &RevisionMismatchError{Word: word, Title: title, RevisionId: revisionId, PageTitle: page.Title}
 	}
-
-	wikitext, err := convertWikitext(match[1])
-	if err != nil {
-		msg := fmt.Sprintf("Wikitext decode error for word '%s'", word)
-		return "", errors.New(msg)
-	}
-
-	return wikitext, nil
+	return page, nil
 }
 
-func convertWikitext(text string) (string, error) {
-	// decode any scripts represented by surrogate pairs - this includes Avestan, for example
-	re := regexp.MustCompile(`\\ud([8-9a-fA-F][0-9a-fA-F]{2})`) // matches the format \udxxx
-	match := re.FindAllStringSubmatch(text, -1)
-	// this returns a slice of matches - we need to pair them up
-	for i := 0; i < len(match); i += 2 {
-		hiByte, _ := strconv.ParseInt(match[i][0][2:], 16, 32)
-		loByte, _ := strconv.ParseInt(match[i+1][0][2:], 16, 32)
-		r := utf16.DecodeRune(rune(hiByte), rune(loByte))
-		textToReplace := match[i][0] + match[i+1][0]
-		replaceWith := string(r)
-		text = strings.ReplaceAll(text, textToReplace, replaceWith)
-	}
-
-	// now decode the more usual backslash-encoded characters
-	// NB strconv.Unquote requires and returns a string enclosed in quotes - we need to handle that
-	convertedText, err := strconv.Unquote(`"` + text + `"`)
-	if err != nil {
-		return "", nil
+func isPageForTitle(page *PageResponse, title string) bool {
+	// whether the page returned is the one with the given title, once the title has been
+	// normalized and any redirects followed as the API reports them
+	for _, change := range page.Normalized {
+		if change.From == title {
+			title = change.To
+		}
 	}
-	convertedText = strings.Trim(convertedText, `"`)
-	return convertedText, nil
-
+	for _, change := range page.Redirects {
+		if change.From == title {
+			title = change.To
+		}
+	}
+	return normalizeTitle(page.Title) == title
 }
 
 func processWikitext(wikitext string) []Section {
@@ -120,6 +101,9 @@ func extractLanguageSections(word string, langCode string, sections []Section) (
 	// find the relevant language sections - this will be all of the sections starting with
 	// ==Language== and up to (but not including) the next ==????== block, or the end of the data
 	languageName := getLanguageFromCode(langCode)
+	if languageName == "" {
+		return nil, &UnknownLanguageCodeError{LangCode: langCode, Suggestions: suggestLanguages(langCode)}
+	}
 	languageHeader := "==" + languageName + "=="
 	startIndex, endIndex := 0, 0
 
@@ -133,8 +117,7 @@ func extractLanguageSections(word string, langCode string, sections []Section) (
 
 	// if there was no start index, return an error
 	if startIndex == 0 {
-		msg := fmt.Sprintf("Word '%s' exists on Wiktionary, but not for %s", word, languageName)
-		return nil, errors.New(msg)
+		return nil, &LanguageNotOnPageError{Word: word, LangCode: langCode, Languages: getPageLanguages(sections)}
 	}
 
 	// find the start of the next language, or the end of the file
@@ -158,6 +141,22 @@ func extractLanguageSections(word string, langCode string, sections []Section) (
 	return langSections, nil
 }
 
+func getPageLanguages(sections []Section) []string {
+	// find the codes of the languages on a page, from its language-level headings
+	var codes []string
+	re := regexp.MustCompile(`^==([^=]+)==$`)
+	for _, section := range sections {
+		match := re.FindStringSubmatch(section.header)
+		if len(match) == 0 {
+			continue
+		}
+		if code := getCodeFromLanguage(strings.TrimSpace(match[1])); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
 func getLanguageFromCode(code string) string {
 	// convert a language code to the full name, e.g. for "en" return "English"
 	return languageCodes[code]
@@ -165,6 +164,8 @@ func getLanguageFromCode(code string) string {
 
 func getPageTitle(word string, langCode string) string {
 	var title string
+	// a word as written in running text may have marks its page title doesn't, e.g. Latin vowel lengths
+	word = getEntryName(word, langCode)
 	// reconstructed words will have an asterisk as the first character and need special handling
 	if strings.HasPrefix(word, "*") {
 		reconLang := "Reconstruction:" + getLanguageFromCode(langCode) + "/"
@@ -173,39 +174,85 @@ func getPageTitle(word string, langCode string) string {
 		// but normallly this is just the word
 		title = word
 	}
-	return title
-
+	// then normalize it, so that e.g. "red_giant" finds the page "red giant"
+	return normalizeTitle(title)
 }
 
-func getTextFromWiktionary(text string, word string, langCode string) (string, error) {
-	// for the given text with tags, retrieve the equivalent text from Wiktionary
-	urlHead := "https://en.wiktionary.org/w/api.php?action=parse&text="
-	urlTail := "&prop=text&title=" + getPageTitle(word, langCode) + "&formatversion=2&format=json"
+func getTextFromWiktionary(ctx context.Context, text string, word string, langCode string, options WiktionaryOptions) (string, error) {
+	// for the given text with tags, retrieve the equivalent HTML from the page source
+	// if we are rendering in batches, this will either already have been rendered, or we are
+	// collecting the texts for the batch
+	if options.batch != nil {
+		if html, ok := options.batch.rendered[text]; ok {
+			return html, nil
+		}
+	}
 
-	// make an HHTP request to Wiktionary
-	url := urlHead + url.QueryEscape(text) + urlTail
-	resp, err := http.Get(url)
-	if err != nil {
+	// once the context is cancelled, stop calling Wiktionary
+	if err := ctx.Err(); err != nil {
 		return "", err
 	}
 
-	// process the response to retrieve the body
-	body, err := ioutil.ReadAll(resp.Body)
+	// check the cache before going any further
+	cache := options.Cache
+	title := getPageTitle(word, langCode)
+	if cache != nil {
+		if html, ok := cache.GetText(text, title); ok {
+			return html, nil
+		}
+	}
+	if options.batch != nil && options.batch.collecting {
+		options.batch.add(text)
+		return "", nil
+	}
+
+	// if the source can't render templates (e.g. it's offline) then return nothing, and
+	// callers will fall back to the original text
+	renderer, ok := getSource(options).(TextRenderer)
+	if !ok {
+		return "", nil
+	}
+	html, err := renderer.RenderText(ctx, text, word, langCode)
 	if err != nil {
 		return "", err
 	}
-	returnedText := string(body)
+	if cache != nil {
+		cache.PutText(text, title, html)
+	}
+	return html, nil
+}
+
+func getTextFromApi(ctx context.Context, source ApiSource, text string, word string, langCode string) (*ParseResponse, error) {
+	// for the given text with tags, retrieve the equivalent HTML from the Wiktionary API
+	params := url.Values{}
+	params.Set("action", "parse")
+	params.Set("text", text)
+	params.Set("prop", "text")
+	params.Set("title", getPageTitle(word, langCode))
+	params.Set("formatversion", "2")
+	params.Set("format", "json")
+
+	// make an HTTP request to Wiktionary - batches of text are too long for a URL, so POST those
+	body, err := source.call(ctx, params, len(text) > maxGetTextLength)
+	if err != nil {
+		return nil, err
+	}
 
-	return returnedText, nil
+	// and decode the JSON to get the rendered HTML
+	return decodeParseResponse(body)
 }
 
-func getConvertedTextFromWiktionary(text string, word string, langCode string) (string, error) {
+func getConvertedTextFromWiktionary(ctx context.Context, text string, word string, langCode string, options WiktionaryOptions) (string, error) {
 	// for the given text with tags, retrieve the converted text from Wiktionary
-	returnedText, _ := getTextFromWiktionary(text, word, langCode)
+	// the common templates can be expanded natively, so only call Wiktionary if there are others
+	if expanded, ok := expandTemplates(text); ok {
+		return expanded, nil
+	}
+	returnedHtml, _ := getTextFromWiktionary(ctx, text, word, langCode, options)
 
-	// strip out the part of interest
-	re := regexp.MustCompile(`text":"(.*?)</p.*>\\n<!--`)
-	match := re.FindStringSubmatch(returnedText)
+	// strip out the part of interest - the first paragraph
+	re := regexp.MustCompile(`(?s)^(.*?)</p`)
+	match := re.FindStringSubmatch(returnedHtml)
 	if len(match) == 0 {
 		// if there is no relevant text, return the original tags, so we at least have something
 		return text, nil
@@ -232,19 +279,17 @@ func getConvertedTextFromWiktionary(text string, word string, langCode string) (
 
 	// strip any newlines at the beginning or end
 	convertedText = strings.Trim(convertedText, "\n")
-	// deal with the situation where we have "\\n" at the end
-	convertedText = strings.TrimSuffix(convertedText, "\\n")
 
 	return convertedText, nil
 }
 
-func getTableFromWiktionary(text string, word string, langCode string) (string, error) {
+func getTableFromWiktionary(ctx context.Context, text string, word string, langCode string, options WiktionaryOptions) (string, error) {
 	// for the given text with tags, retrieve the equivalent HTML from Wiktionary
-	returnedText, _ := getTextFromWiktionary(text, word, langCode)
+	returnedHtml, _ := getTextFromWiktionary(ctx, text, word, langCode, options)
 
 	// strip out the part of interest - in this case the tables
-	re := regexp.MustCompile(`(<table.*?</table>)`)
-	match := re.FindStringSubmatch(returnedText)
+	re := regexp.MustCompile(`(?s)(<table.*?</table>)`)
+	match := re.FindStringSubmatch(returnedHtml)
 	if len(match) == 0 {
 		// if there is no relevant text, return the original tags, so we at least have something
 		return text, nil
@@ -0,0 +1,121 @@
+package wiktionary
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// the etymology templates for each relationship, by every name they go by on Wiktionary,
+// e.g. {{bor|en|fr|rouge}} or {{borrowed|en|fr|rouge}}
+var relationshipTemplates = map[string]RelationshipType{
+	"root":                    Root,
+	"inh":                     Inherited,
+	"inh+":                    Inherited,
+	"inh-lite":                Inherited,
+	"inherited":               Inherited,
+	"bor":                     Borrowed,
+	"bor+":                    Borrowed,
+	"bor-lite":                Borrowed,
+	"borrowed":                Borrowed,
+	"ubor":                    Borrowed,
+	"unadapted borrowing":     Borrowed,
+	"lbor":                    LearnedBorrowing,
+	"learned borrowing":       LearnedBorrowing,
+	"obor":                    OrthographicBorrowing,
+	"orthographic borrowing":  OrthographicBorrowing,
+	"slbor":                   SemiLearnedBorrowing,
+	"semi-learned borrowing":  SemiLearnedBorrowing,
+	"der":                     Derived,
+	"der+":                    Derived,
+	"der-lite":                Derived,
+	"derived":                 Derived,
+	"uder":                    Derived,
+	"undefined derivation":    Derived,
+	"cal":                     Calque,
+	"clq":                     Calque,
+	"calque":                  Calque,
+	"pcal":                    PartialCalque,
+	"pclq":                    PartialCalque,
+	"partial calque":          PartialCalque,
+	"sl":                      SemanticLoan,
+	"semantic loan":           SemanticLoan,
+	"psm":                     PhonoSemanticMatching,
+	"phono-semantic matching": PhonoSemanticMatching,
+	"m":                       Mention,
+	"m+":                      Mention,
+	"mention":                 Mention,
+	"cog":                     Cognate,
+	"cognate":                 Cognate,
+	"desc":                    Descendant,
+	"descendant":              Descendant,
+	"desctree":                Descendant,
+}
+
+// flags which older entries put on a {{der}} or {{inh}} template to say what kind of derivation it is
+var relationshipFlags = []struct {
+	flag         string
+	relationship RelationshipType
+}{
+	{"bor", Borrowed},
+	{"lbor", LearnedBorrowing},
+	{"slb", SemiLearnedBorrowing},
+	{"clq", Calque},
+	{"pclq", PartialCalque},
+	{"sml", SemanticLoan},
+}
+
+func getRelationship(templateName string) (RelationshipType, bool) {
+	// the relationship of an etymology template, e.g. Borrowed for {{bor}}
+	relationship, ok := relationshipTemplates[strings.TrimSpace(templateName)]
+	return relationship, ok
+}
+
+func getFlaggedRelationship(relationship RelationshipType, args map[string]string) RelationshipType {
+	// a general derivation may say more precisely what it is with a flag, e.g. {{der|en|fr|x|bor=1}}
+	if relationship != Derived && relationship != Inherited {
+		return relationship
+	}
+	for _, f := range relationshipFlags {
+		if _, ok := args[f.flag]; ok {
+			return f.relationship
+		}
+	}
+	return relationship
+}
+
+// IsAncestor reports whether a word with this relationship is one the word came from, rather than
+// a root, a relative or a word which is merely mentioned - a relationship this package doesn't
+// know, e.g. one read from JSON, isn't taken to be an ancestor
+func IsAncestor(relationship RelationshipType) bool {
+	switch relationship {
+	case Inherited, Borrowed, LearnedBorrowing, OrthographicBorrowing, SemiLearnedBorrowing, Derived,
+		Calque, PartialCalque, SemanticLoan, PhonoSemanticMatching:
+		return true
+	}
+	return false
+}
+
+func (l *LinkedWord) UnmarshalJSON(b []byte) error {
+	// JSON written before there were distinct relationships has everything from {{bor}}, {{der}}
+	// and the like as "inherited", with the real relationship in the attributes - and the name of
+	// a template for the relationship is accepted too, e.g. "bor"
+	type linkedWord LinkedWord
+	if err := json.Unmarshal(b, (*linkedWord)(l)); err != nil {
+		return err
+	}
+	if relationship, ok := getRelationship(l.Relationship); ok {
+		l.Relationship = relationship
+	}
+	if l.Relationship == Inherited {
+		for _, attr := range []struct {
+			name         string
+			relationship RelationshipType
+		}{{"calque", Calque}, {"semantic loan", SemanticLoan}, {"borrowed", Borrowed}, {"derived", Derived}} {
+			if l.Attributes[attr.name] {
+				l.Relationship = attr.relationship
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package wiktionary
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRelationshipTypes(t *testing.T) {
+	wikitext := `==English==
+
+===Etymology===
+{{root|en|ine-pro|*h₁rewdʰ-}} {{inh|en|enm|rede}}, {{bor|en|fr|rouge}}, {{lbor|en|la|ruber}}, {{obor|en|fr|rougé}},
+{{slbor|en|la|rubeus}}, {{der|en|gem-pro|*raudaz}}, {{cal|en|de|Rotkohl}}, {{pcal|en|fr|rouge-gorge}},
+{{sl|en|fr|rouge}}, {{psm|en|zh|紅}}, {{der|en|la|rufus|bor=1}}, {{m|en|read}}, {{cog|de|rot}}, {{ncog|grc|ἐρυθρός}}
+
+===Adjective===
+{{en-adj}}
+
+# Having red as its colour.
+`
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{"red": wikitext}
+	lw, err := (&Client{}).GetWordWithOptions("red", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+
+	// {{m}} is only mentioned, and {{ncog}} is a word which isn't related at all, so neither is kept
+	expected := []struct {
+		word         string
+		relationship RelationshipType
+	}{
+		{"*h₁rewdʰ-", Root}, {"rede", Inherited}, {"rouge", Borrowed}, {"ruber", LearnedBorrowing},
+		{"rougé", OrthographicBorrowing}, {"rubeus", SemiLearnedBorrowing}, {"*raudaz", Derived},
+		{"Rotkohl", Calque}, {"rouge-gorge", PartialCalque}, {"rouge", SemanticLoan}, {"紅", PhonoSemanticMatching},
+		{"rufus", Borrowed}, {"rot", Cognate},
+	}
+	words := lw.Etymologies[0].Words
+	if len(words) != len(expected) {
+		t.Fatalf(`GetWordWithOptions: expected %d linked words, got %+v`, len(expected), words)
+	}
+	for i, exp := range expected {
+		if words[i].Word != exp.word || words[i].Relationship != exp.relationship {
+			t.Fatalf(`GetWordWithOptions: expected %q to be %q, got %q %q`, exp.word, exp.relationship, words[i].Word, words[i].Relationship)
+		}
+	}
+	// older callers can still tell a borrowing by its attributes
+	if !words[2].Attributes["borrowed"] || !words[7].Attributes["calque"] {
+		t.Fatalf(`GetWordWithOptions: expected the borrowed and calque attributes, got %v and %v`, words[2].Attributes, words[7].Attributes)
+	}
+	if !IsAncestor(words[1].Relationship) || !IsAncestor(words[2].Relationship) || IsAncestor(words[12].Relationship) {
+		t.Fatalf(`IsAncestor: expected inherited and borrowed words to be ancestors, but not cognates`)
+	}
+	if IsAncestor("unknown") || IsAncestor("") {
+		t.Fatalf(`IsAncestor: expected an unknown relationship not to be an ancestor`)
+	}
+}
+
+func TestRelationshipJson(t *testing.T) {
+	// JSON written before there were distinct relationships has borrowings as "inherited"
+	old := `[{"type":"inherited","lang":"fr","word":"rouge","attrs":{"borrowed":true}},
+		{"type":"inherited","lang":"enm","word":"rede"},
+		{"type":"bor","lang":"fr","word":"rouge"},
+		{"type":"cognate","lang":"de","word":"rot"}]`
+	var words []LinkedWord
+	if err := json.Unmarshal([]byte(old), &words); err != nil {
+		t.Fatalf(`Error from json.Unmarshal: %q`, err)
+	}
+	expected := []RelationshipType{Borrowed, Inherited, Borrowed, Cognate}
+	for i, relationship := range expected {
+		if words[i].Relationship != relationship {
+			t.Fatalf(`json.Unmarshal: expected %q for %q, got %q`, relationship, words[i].Word, words[i].Relationship)
+		}
+	}
+
+	// the relationships are still strings, so callers comparing them with strings keep working
+	var relationship string = words[1].Relationship
+	if relationship != "inherited" {
+		t.Fatalf(`json.Unmarshal: expected "inherited" for %q, got %q`, words[1].Word, relationship)
+	}
+
+	// and the relationship is written as it always was, as a string
+	b, err := json.Marshal(LinkedWord{Relationship: LearnedBorrowing, Language: "la", Word: "ruber"})
+	if err != nil {
+		t.Fatalf(`Error from json.Marshal: %q`, err)
+	}
+	if string(b) != `{"type":"learned borrowing","lang":"la","word":"ruber"}` {
+		t.Fatalf(`json.Marshal: expected the relationship as a string, got %s`, b)
+	}
+}
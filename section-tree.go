@@ -0,0 +1,83 @@
+package wiktionary
+
+import "strings"
+
+// a sectionNode is a section together with the sections nested under it - e.g. under
+// ===Etymology 2=== are its ====Pronunciation==== and ====Noun====, and under the noun
+// its =====Translations=====
+type sectionNode struct {
+	Section
+	level    int
+	children []*sectionNode
+}
+
+// the entry a section belongs to - the indexes of the etymology and part of speech it is
+// nested under in the LanguageWord, or -1 if it isn't nested under one
+type sectionScope struct {
+	etym int
+	part int
+}
+
+var languageScope = sectionScope{etym: -1, part: -1}
+
+func getHeaderLevel(header string) int {
+	// the level of a heading is the number of = around it, e.g. 2 for ==English==
+	// if the two sides don't match, MediaWiki uses the smaller
+	left := len(header) - len(strings.TrimLeft(header, "="))
+	right := len(header) - len(strings.TrimRight(header, "="))
+	if right < left {
+		return right
+	}
+	return left
+}
+
+func buildSectionTree(sections []Section) *sectionNode {
+	// nest the sections of a language by their heading levels - the first section is the
+	// language header, and becomes the root of the tree
+	if len(sections) == 0 {
+		return &sectionNode{}
+	}
+	root := &sectionNode{Section: sections[0], level: getHeaderLevel(sections[0].header)}
+	stack := []*sectionNode{root}
+	for _, section := range sections[1:] {
+		node := &sectionNode{Section: section, level: getHeaderLevel(section.header)}
+		// a section belongs to the nearest section above it with a lower level - anything at
+		// the language's level or above still belongs to the language
+		for len(stack) > 1 && stack[len(stack)-1].level >= node.level {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+		stack = append(stack, node)
+	}
+	return root
+}
+
+func (s sectionScope) etymology(lw *LanguageWord) *Etymology {
+	// the etymology the section is nested under - a section at the language level, as on
+	// pages with a single etymology, belongs to the latest one
+	if s.etym >= 0 {
+		return &lw.Etymologies[s.etym]
+	}
+	if len(lw.Etymologies) == 0 {
+		return nil
+	}
+	return &lw.Etymologies[len(lw.Etymologies)-1]
+}
+
+func (s sectionScope) partOfSpeech(lw *LanguageWord) *PartOfSpeech {
+	// the part of speech the section is nested under - if it isn't nested under one (e.g. a
+	// Translations section at the same level as its Noun) it belongs to the latest part in
+	// its etymology
+	etym := s.etymology(lw)
+	if etym == nil {
+		return nil
+	}
+	if s.etym >= 0 && s.part >= 0 {
+		return &etym.Parts[s.part]
+	}
+	if len(etym.Parts) == 0 {
+		return nil
+	}
+	return &etym.Parts[len(etym.Parts)-1]
+}
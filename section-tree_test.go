@@ -0,0 +1,107 @@
+package wiktionary
+
+import "testing"
+
+const testHomographWikitext = `==English==
+
+===Pronunciation===
+* /lɪd/
+
+===Etymology 1===
+From Old English lēad.
+
+====Pronunciation====
+* /lɛd/
+
+====Noun====
+{{en-noun}}
+
+# A heavy metal.
+
+=====Translations=====
+* French: {{t|fr|plomb|m}}
+
+=====Synonyms=====
+* plumbum
+
+===Etymology 2===
+From Old English lǣdan.
+
+====Verb====
+{{en-verb}}
+
+# To guide.
+
+=====Translations=====
+* French: {{t|fr|mener}}
+
+=====Synonyms=====
+* guide
+
+====Noun====
+{{en-noun}}
+
+# The front position.
+
+==Dutch==
+
+===Noun===
+{{nl-noun}}
+
+# A Dutch word.`
+
+func TestBuildSectionTree(t *testing.T) {
+	sections, err := extractLanguageSections("lead", "en", processWikitext(testHomographWikitext))
+	if err != nil {
+		t.Fatalf(`Error from extractLanguageSections: %q`, err)
+	}
+	tree := buildSectionTree(sections)
+	if tree.header != "==English==" || len(tree.children) != 3 {
+		t.Fatalf(`buildSectionTree: expected English with 3 sections under it, got %q with %d`, tree.header, len(tree.children))
+	}
+	etym2 := tree.children[2]
+	if etym2.header != "===Etymology 2===" || etym2.level != 3 || len(etym2.children) != 2 {
+		t.Fatalf(`buildSectionTree: expected Etymology 2 with 2 sections under it, got %q with %d`, etym2.header, len(etym2.children))
+	}
+	verb := etym2.children[0]
+	if verb.header != "====Verb====" || len(verb.children) != 2 || verb.children[1].header != "=====Synonyms=====" {
+		t.Fatalf(`buildSectionTree: expected Verb with its Translations and Synonyms, got %q with %d`, verb.header, len(verb.children))
+	}
+
+	if level := getHeaderLevel("===Noun=="); level != 2 {
+		t.Fatalf(`getHeaderLevel: expected the smaller side for an unbalanced heading, got %d`, level)
+	}
+}
+
+func TestNestedSections(t *testing.T) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.RequiredLanguages = []string{"fr"}
+	options.Source = MapSource{"lead": testHomographWikitext}
+	lw, err := (&Client{}).GetWordWithOptions("lead", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+
+	// the pronunciation at the language level belongs to the word, the nested one to its etymology
+	if lw.Ipa != "/lɪd/" || lw.Etymologies[0].Ipa != "/lɛd/" || lw.Etymologies[1].Ipa != "" {
+		t.Fatalf(`GetWordWithOptions: expected IPA /lɪd/ for the word and /lɛd/ for Etymology 1 only, got %q, %q and %q`,
+			lw.Ipa, lw.Etymologies[0].Ipa, lw.Etymologies[1].Ipa)
+	}
+
+	// each part of speech gets the translations and synonyms nested under it, not the latest one
+	if len(lw.Etymologies) != 2 || len(lw.Etymologies[1].Parts) != 2 {
+		t.Fatalf(`GetWordWithOptions: expected 2 etymologies, the second with 2 parts, got %+v`, lw.Etymologies)
+	}
+	noun := lw.Etymologies[0].Parts[0]
+	if len(noun.Translations) != 1 || noun.Translations[0].Word != "plomb" || noun.Synonyms != "plumbum\n" {
+		t.Fatalf(`GetWordWithOptions: expected the noun's translation and synonym, got %+v and %q`, noun.Translations, noun.Synonyms)
+	}
+	verb := lw.Etymologies[1].Parts[0]
+	if len(verb.Translations) != 1 || verb.Translations[0].Word != "mener" || verb.Synonyms != "guide\n" {
+		t.Fatalf(`GetWordWithOptions: expected the verb's translation and synonym, got %+v and %q`, verb.Translations, verb.Synonyms)
+	}
+	if noun2 := lw.Etymologies[1].Parts[1]; len(noun2.Translations) != 0 || noun2.Synonyms != "" {
+		t.Fatalf(`GetWordWithOptions: expected nothing nested under the second noun, got %+v and %q`, noun2.Translations, noun2.Synonyms)
+	}
+}
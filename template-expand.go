@@ -0,0 +1,522 @@
+package wiktionary
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// a templateExpander renders a single template, given its positional and named arguments
+// (positional arguments are numbered from 1, as on Wiktionary) - it returns false if it can't
+// render the template faithfully, in which case the whole line is sent to the API instead
+type templateExpander func(args map[string]string) (string, bool)
+
+var templateExpanders map[string]templateExpander
+
+func init() {
+	// etymology templates - the text shown before the language, when the template has one
+	etymologyTemplates := map[string]string{
+		"inh": "", "inherited": "", "bor": "", "borrowed": "", "der": "", "derived": "",
+		"inh+": "Inherited from", "bor+": "Borrowed from", "der+": "Derived from",
+		"lbor": "Learned borrowing from", "learned borrowing": "Learned borrowing from",
+		"slbor": "Semi-learned borrowing from", "semi-learned borrowing": "Semi-learned borrowing from",
+		"obor": "Orthographic borrowing from", "orthographic borrowing": "Orthographic borrowing from",
+		"ubor": "Unadapted borrowing from", "unadapted borrowing": "Unadapted borrowing from",
+		"cal": "Calque of", "clq": "Calque of", "calque": "Calque of",
+		"pcal": "Partial calque of", "pclq": "Partial calque of", "partial calque": "Partial calque of",
+		"sl": "Semantic loan from", "semantic loan": "Semantic loan from",
+		"psm": "Phono-semantic matching of", "phono-semantic matching": "Phono-semantic matching of",
+	}
+	templateExpanders = make(map[string]templateExpander)
+	for name, prefix := range etymologyTemplates {
+		templateExpanders[name] = expandEtymologyTemplate(prefix)
+	}
+	for _, name := range []string{"cog", "cognate", "ncog", "noncog", "noncognate"} {
+		templateExpanders[name] = expandCognateTemplate
+	}
+	for _, name := range []string{"m", "mention", "l", "link"} {
+		templateExpanders[name] = expandLinkTemplate
+	}
+	for _, name := range []string{"gloss", "gl"} {
+		templateExpanders[name] = expandGlossTemplate
+	}
+	for _, name := range []string{"q", "qual", "qualifier", "i", "qf"} {
+		templateExpanders[name] = expandQualifierTemplate
+	}
+	for _, name := range []string{"lb", "lbl", "label"} {
+		templateExpanders[name] = expandLabelTemplate
+	}
+	for _, name := range []string{"sense", "s"} {
+		templateExpanders[name] = expandSenseTemplate
+	}
+	for _, name := range []string{"unk", "unknown"} {
+		templateExpanders[name] = expandFixedTextTemplate("Unknown")
+	}
+	for _, name := range []string{"unc", "uncertain"} {
+		templateExpanders[name] = expandFixedTextTemplate("Uncertain")
+	}
+	for _, name := range []string{"onom", "onomatopoeic"} {
+		templateExpanders[name] = expandFixedTextTemplate("Onomatopoeic")
+	}
+	for _, name := range []string{"root", "senseid", "anchor"} {
+		templateExpanders[name] = func(args map[string]string) (string, bool) { return "", true } // these render nothing
+	}
+	templateExpanders["ll"] = expandLinkLiteTemplate
+	templateExpanders["desc"] = expandDescendantTemplate
+	templateExpanders["descendant"] = expandDescendantTemplate
+	templateExpanders["etyl"] = expandEtylTemplate
+	templateExpanders["w"] = expandWikipediaTemplate
+	for _, name := range []string{"taxlink", "vern", "n-g", "ngd", "non-gloss definition"} {
+		templateExpanders[name] = expandFirstArgTemplate
+	}
+}
+
+// named arguments which only affect categories or links, not the rendered text
+var ignoredTemplateArgs = map[string]bool{
+	"id": true, "sc": true, "nocat": true, "sort": true, "nocap": true, "notext": true, "senseid": true,
+}
+
+// labels which are joined to the next label by a space rather than a comma, e.g. "(chiefly derogatory)"
+var labelsWithoutComma = map[string]bool{
+	"also": true, "and": true, "chiefly": true, "mainly": true, "mostly": true, "now": true, "of": true,
+	"or": true, "primarily": true, "usually": true, "especially": true, "often": true, "sometimes": true,
+	"by": true, "with": true, "except": true, "in": true, "at": true, "possibly": true, "rarely": true,
+	"particularly": true, "than": true, "since": true, "until": true, "from": true, "outside": true,
+}
+
+// label aliases which are displayed differently from how they are written
+var labelAliases = map[string]string{
+	"intr": "intransitive", "tr": "transitive", "math": "mathematics", "maths": "mathematics",
+	"chem": "chemistry", "comp": "computing", "AU": "Australia", "NZ": "New Zealand",
+}
+
+func expandTemplates(text string) (string, bool) {
+	// render the given line of wikitext without calling Wiktionary
+	// returns false if there is anything we can't render exactly as Wiktionary would
+
+	// strip any list markup, leaving the text of the list item
+	text = strings.TrimLeft(text, "*#:; ")
+
+	text, ok := expandNodes(ParseWikitext(text))
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSpace(html.UnescapeString(text)), true
+}
+
+func expandNodes(nodes []Node) (string, bool) {
+	// render the nodes as text - comments are never rendered, and links show as their text,
+	// but anything else which changes the HTML (e.g. a <ref>) needs the real parser
+	var text strings.Builder
+	for _, node := range nodes {
+		switch node.Type {
+		case Node_Text:
+			// anything left over which looks like markup wasn't well formed
+			for _, markup := range []string{"{{", "}}", "[[", "]]", "<", "[http"} {
+				if strings.Contains(node.Text, markup) {
+					return "", false
+				}
+			}
+			text.WriteString(node.Text)
+		case Node_Comment:
+		case Node_Nowiki:
+			text.WriteString(node.Text)
+		case Node_Bold, Node_Italic:
+			// bold and italic quotes have no text of their own
+			inner, ok := expandNodes(node.Children)
+			if !ok {
+				return "", false
+			}
+			text.WriteString(inner)
+		case Node_Template:
+			expanded, ok := expandTemplate(&node)
+			if !ok {
+				return "", false
+			}
+			text.WriteString(expanded)
+		case Node_Link:
+			display, ok := expandLink(&node)
+			if !ok {
+				return "", false
+			}
+			text.WriteString(display)
+		default:
+			return "", false
+		}
+	}
+	return text.String(), true
+}
+
+func expandTemplate(tmpl *Node) (string, bool) {
+	// render a single template, if we know how to
+	expander, known := templateExpanders[tmpl.Text]
+	if !known {
+		return "", false
+	}
+	// nested templates and links inside templates need the real parser
+	args := make(map[string]string, len(tmpl.Args))
+	for _, arg := range tmpl.Args {
+		var val strings.Builder
+		for _, node := range arg.Value {
+			switch node.Type {
+			case Node_Text:
+				val.WriteString(node.Text)
+			case Node_Comment:
+			default:
+				return "", false
+			}
+		}
+		args[arg.Name] = val.String()
+		if !arg.Positional {
+			args[arg.Name] = strings.TrimSpace(val.String())
+		}
+	}
+	return expander(args)
+}
+
+func expandLink(link *Node) (string, bool) {
+	// render a [[link]] as its text, and remove inline categories
+	target := link.Text
+	if strings.HasPrefix(target, "Category:") {
+		return "", true
+	}
+	// files and images need the real parser
+	if strings.HasPrefix(target, "File:") || strings.HasPrefix(target, "Image:") {
+		return "", false
+	}
+	if len(link.Children) > 0 {
+		display, ok := expandNodes(link.Children)
+		if !ok {
+			return "", false
+		}
+		// only the last part is shown, e.g. [[a|b|c]] shows as c
+		if i := strings.LastIndex(display, "|"); i >= 0 {
+			display = display[i+1:]
+		}
+		return display, true
+	}
+	display := target
+	// links to other wikis show without their prefix, e.g. [[w:Red]] shows as Red
+	if i := strings.Index(display, ":"); i >= 0 {
+		display = display[i+1:]
+	}
+	// and links to sections show without the section
+	if i := strings.Index(display, "#"); i > 0 {
+		display = display[:i]
+	}
+	return display, true
+}
+
+func hasOnlyArgs(args map[string]string, allowed ...string) bool {
+	// check for named arguments which would change the output in ways we don't handle
+	for key := range args {
+		if isPositionalArg(key) || ignoredTemplateArgs[key] {
+			continue
+		}
+		found := false
+		for _, a := range allowed {
+			if key == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func isPositionalArg(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func expandEtymologyTemplate(prefix string) templateExpander {
+	return func(args map[string]string) (string, bool) {
+		// {{inh|en|enm|red|alt|gloss}} renders as "Middle English red (“gloss”)"
+		if !hasOnlyArgs(args, "tr", "ts", "t", "gloss", "pos", "lit", "alt") {
+			return "", false
+		}
+		langName, ok := languageCodes[args["2"]]
+		if !ok {
+			return "", false
+		}
+		text := langName
+		if prefix != "" && args["notext"] != "1" {
+			// the prefix is shared by every use of the template, so it's only lowered here
+			lead := prefix
+			if args["nocap"] == "1" {
+				lead = strings.ToLower(prefix[:1]) + prefix[1:]
+			}
+			text = lead + " " + text
+		}
+		term, ok := formatLinkedTerm(args["3"], args["4"], args["5"], args)
+		if !ok {
+			return "", false
+		}
+		if term != "" {
+			text += " " + term
+		}
+		return text, true
+	}
+}
+
+func expandCognateTemplate(args map[string]string) (string, bool) {
+	// {{cog|fy|read}} renders as "West Frisian read"
+	term, ok := expandLinkTemplate(args)
+	if !ok {
+		return "", false
+	}
+	if term == "" {
+		return languageCodes[args["1"]], true
+	}
+	return languageCodes[args["1"]] + " " + term, true
+}
+
+func expandLinkTemplate(args map[string]string) (string, bool) {
+	// {{m|en|red}} renders as "red"
+	if !hasOnlyArgs(args, "tr", "ts", "t", "gloss", "pos", "lit", "alt") {
+		return "", false
+	}
+	if _, ok := languageCodes[args["1"]]; !ok {
+		return "", false
+	}
+	term, ok := formatLinkedTerm(args["2"], args["3"], args["4"], args)
+	if !ok {
+		return "", false
+	}
+	return term, true
+}
+
+func expandLinkLiteTemplate(args map[string]string) (string, bool) {
+	// {{ll|en|red}} renders as just the term, with no annotations
+	if !hasOnlyArgs(args) {
+		return "", false
+	}
+	if args["3"] != "" {
+		return args["3"], true
+	}
+	return args["2"], true
+}
+
+func formatLinkedTerm(term string, alt string, gloss string, args map[string]string) (string, bool) {
+	// format a term as it's shown by the linking templates, e.g. "रुधिर (rudhirá, “red, bloody”)"
+	if val, ok := args["alt"]; ok && alt == "" {
+		alt = val
+	}
+	if alt != "" {
+		term = alt
+	}
+	if term == "-" {
+		term = ""
+	}
+	tr := args["tr"]
+	// Wiktionary transliterates non-Latin scripts automatically, which we can't do here
+	latinRe := regexp.MustCompile(`\p{Latin}`)
+	letterRe := regexp.MustCompile(`\pL`)
+	if term != "" && tr == "" && letterRe.MatchString(term) && !latinRe.MatchString(term) {
+		return "", false
+	}
+
+	if gloss == "" {
+		gloss = args["t"]
+	}
+	if gloss == "" {
+		gloss = args["gloss"]
+	}
+	var annotations []string
+	if tr != "" {
+		annotations = append(annotations, tr)
+	}
+	if ts := args["ts"]; ts != "" {
+		annotations = append(annotations, "/"+ts+"/")
+	}
+	if gloss != "" {
+		annotations = append(annotations, "“"+gloss+"”")
+	}
+	if pos := args["pos"]; pos != "" {
+		annotations = append(annotations, pos)
+	}
+	if lit := args["lit"]; lit != "" {
+		annotations = append(annotations, "literally “"+lit+"”")
+	}
+	if len(annotations) == 0 {
+		return term, true
+	}
+	if term == "" {
+		return "(" + strings.Join(annotations, ", ") + ")", true
+	}
+	return term + " (" + strings.Join(annotations, ", ") + ")", true
+}
+
+func expandGlossTemplate(args map[string]string) (string, bool) {
+	// {{gloss|red}} renders as "(red)"
+	if !hasOnlyArgs(args) || args["1"] == "" {
+		return "", false
+	}
+	return "(" + args["1"] + ")", true
+}
+
+func expandQualifierTemplate(args map[string]string) (string, bool) {
+	// {{q|often|formal}} renders as "(often, formal)"
+	if !hasOnlyArgs(args) {
+		return "", false
+	}
+	quals := getPositionalArgs(args, 1)
+	if len(quals) == 0 {
+		return "", false
+	}
+	return "(" + strings.Join(quals, ", ") + ")", true
+}
+
+func expandSenseTemplate(args map[string]string) (string, bool) {
+	// {{sense|colour}} renders as "(colour):"
+	if !hasOnlyArgs(args) {
+		return "", false
+	}
+	senses := getPositionalArgs(args, 1)
+	if len(senses) == 0 {
+		return "", false
+	}
+	return "(" + strings.Join(senses, ", ") + "):", true
+}
+
+func expandLabelTemplate(args map[string]string) (string, bool) {
+	// {{lb|en|chiefly|derogatory|offensive}} renders as "(chiefly derogatory, offensive)"
+	if !hasOnlyArgs(args) {
+		return "", false
+	}
+	labels := getPositionalArgs(args, 2)
+	if len(labels) == 0 {
+		return "", false
+	}
+	text := ""
+	sep := ""
+	for _, label := range labels {
+		// an underscore joins the labels either side of it with a space
+		if label == "_" {
+			sep = " "
+			continue
+		}
+		if val, ok := labelAliases[label]; ok {
+			label = val
+		}
+		if text != "" {
+			if label == "and" || label == "or" {
+				text += " "
+			} else {
+				text += sep
+			}
+		}
+		text += label
+		if labelsWithoutComma[label] {
+			sep = " "
+		} else {
+			sep = ", "
+		}
+	}
+	return "(" + text + ")", true
+}
+
+func expandDescendantTemplate(args map[string]string) (string, bool) {
+	// {{desc|enm|grene}} renders as "Middle English: grene"
+	if !hasOnlyArgs(args, "tr", "t", "gloss", "alt") {
+		return "", false
+	}
+	langName, ok := languageCodes[args["1"]]
+	if !ok {
+		return "", false
+	}
+	terms := getPositionalArgs(args, 2)
+	if len(terms) == 0 {
+		return langName, true
+	}
+	// the annotations only apply when there's a single term
+	if len(terms) > 1 {
+		if _, ok := args["tr"]; ok {
+			return "", false
+		}
+		for _, term := range terms {
+			if _, ok := formatLinkedTerm(term, "", "", map[string]string{}); !ok {
+				return "", false
+			}
+		}
+		return langName + ": " + strings.Join(terms, ", "), true
+	}
+	term, ok := formatLinkedTerm(terms[0], "", "", args)
+	if !ok {
+		return "", false
+	}
+	return langName + ": " + term, true
+}
+
+func expandEtylTemplate(args map[string]string) (string, bool) {
+	// {{etyl|la|en}} renders as "Latin"
+	if !hasOnlyArgs(args) {
+		return "", false
+	}
+	langName, ok := languageCodes[args["1"]]
+	return langName, ok
+}
+
+func expandWikipediaTemplate(args map[string]string) (string, bool) {
+	// {{w|Red}} renders as "Red", {{w|Red (colour)|red}} as "red"
+	if !hasOnlyArgs(args, "lang") {
+		return "", false
+	}
+	if args["2"] != "" {
+		return args["2"], true
+	}
+	return args["1"], args["1"] != ""
+}
+
+func expandFirstArgTemplate(args map[string]string) (string, bool) {
+	// templates which just format their first argument, e.g. {{taxlink|Sciaenops ocellatus|species}}
+	if !hasOnlyArgs(args, "i", "nomul") || args["1"] == "" {
+		return "", false
+	}
+	return args["1"], true
+}
+
+func expandFixedTextTemplate(text string) templateExpander {
+	return func(args map[string]string) (string, bool) {
+		// e.g. {{unk|en}} renders as "Unknown"
+		if !hasOnlyArgs(args, "title") {
+			return "", false
+		}
+		if args["notext"] == "1" {
+			return "", true
+		}
+		if val, ok := args["title"]; ok {
+			return val, true
+		}
+		if args["nocap"] == "1" {
+			return strings.ToLower(text[:1]) + text[1:], true
+		}
+		return text, true
+	}
+}
+
+func getPositionalArgs(args map[string]string, from int) []string {
+	// return the non-empty positional arguments from the given position onwards
+	var vals []string
+	for i := from; ; i++ {
+		val, ok := args[strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		if val = strings.TrimSpace(val); val != "" {
+			vals = append(vals, val)
+		}
+	}
+	return vals
+}
@@ -0,0 +1,58 @@
+package wiktionary
+
+import "testing"
+
+func TestExpandTemplates(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"From {{inh|en|enm|red}}, from {{inh|en|ang|rēad}}, from {{inh|en|gmw-pro|*raud}}",
+			"From Middle English red, from Old English rēad, from Proto-West Germanic *raud"},
+		{"(compare {{cog|fy|read}}, {{cog|nds|root}}, {{m|nds|rod}})",
+			"(compare West Frisian read, Low German root, rod)"},
+		{"{{cog|sq|pruth||redhead}}", "Albanian pruth (“redhead”)"},
+		{"{{cog|sa|रुधिर||red, bloody|tr=rudhirá}}", "Sanskrit रुधिर (rudhirá, “red, bloody”)"},
+		{"{{der|en|la|-}}", "Latin"},
+		{"{{bor+|en|fr|rouge}}", "Borrowed from French rouge"},
+		{"{{lbor|en|la|rubeus|nocap=1}}", "learned borrowing from Latin rubeus"},
+		{"{{lbor|en|la|rubeus}}", "Learned borrowing from Latin rubeus"},
+		{"{{cal|en|de|Weltanschauung|notext=1}}", "German Weltanschauung"},
+		{"{{root|en|ine-pro|*h₁rewdʰ-}}From {{inh|en|enm|red|t=the colour}}", "From Middle English red (“the colour”)"},
+		{"{{lb|en|particle physics}} Having a [[color charge]] of [[red]].", "(particle physics) Having a color charge of red."},
+		{"{{lb|en|chiefly|derogatory|offensive}} [[Amerind]]", "(chiefly derogatory, offensive) Amerind"},
+		{"{{lb|en|often|capitalized}} Supportive", "(often capitalized) Supportive"},
+		{"{{lb|en|countable|_|and|_|uncountable}} x", "(countable and uncountable) x"},
+		{"{{lb|en|US|colloquial|uncountable}} [[chili con carne]]", "(US, colloquial, uncountable) chili con carne"},
+		{"{{sense|having red as its colour}} {{l|en|nonred}}, {{l|en|unred}}", "(having red as its colour): nonred, unred"},
+		{"{{q|informal}} {{gloss|a redshank}}", "(informal) (a redshank)"},
+		{"* {{desc|enm|grene}}", "Middle English: grene"},
+		{"'''red''' [[w:Red|Red]] <!-- comment -->", "red Red"},
+	}
+	for _, test := range tests {
+		output, ok := expandTemplates(test.input)
+		if !ok {
+			t.Fatalf(`expandTemplates(%q): expected it to be expanded`, test.input)
+		}
+		if output != test.expected {
+			t.Fatalf(`expandTemplates(%q): expected %q, got %q`, test.input, test.expected, output)
+		}
+	}
+}
+
+func TestExpandTemplatesFallback(t *testing.T) {
+	// these all need Wiktionary to render them
+	tests := []string{
+		"{{en-adj|redder|more}}",
+		"{{IPA|en|/ɹɛd/}}",
+		"{{cog|grc|ἐρυθρός}}",      // needs an automatic transliteration
+		"{{der|en|la|{{m|la|x}}}}", // nested template
+		"red<ref>a reference</ref>",
+		"{{inh|en|xx-unknown|red}}",
+	}
+	for _, test := range tests {
+		if output, ok := expandTemplates(test); ok {
+			t.Fatalf(`expandTemplates(%q): expected a fallback, got %q`, test, output)
+		}
+	}
+}
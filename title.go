@@ -0,0 +1,62 @@
+package wiktionary
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// the namespaces a word's page can be found in, other than the main namespace - a prefix
+// matching one of these in any case is written the way MediaWiki writes it
+var titleNamespaces = []string{"Reconstruction", "Appendix"}
+
+// the most redirects followed before giving up, as MediaWiki itself does
+const maxRedirects = 5
+
+func normalizeTitle(title string) string {
+	// normalize a page title the way MediaWiki does - underscores are spaces, runs of spaces are
+	// collapsed, leading and trailing spaces removed, and the text put into Unicode NFC form
+	// NB Wiktionary doesn't capitalize the first letter of titles, so "Red" and "red" are
+	// different pages and both are left as they are
+	title = strings.ReplaceAll(title, "_", " ")
+	title = strings.Join(strings.Fields(title), " ")
+	title = norm.NFC.String(title)
+
+	// a title can't have a fragment, and a leading colon just means the main namespace
+	if i := strings.Index(title, "#"); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	title = strings.TrimPrefix(title, ":")
+
+	// namespace names are case-insensitive, and can be followed by spaces
+	if i := strings.Index(title, ":"); i > 0 {
+		for _, ns := range titleNamespaces {
+			if strings.EqualFold(strings.TrimSpace(title[:i]), ns) {
+				title = ns + ":" + strings.TrimSpace(title[i+1:])
+				break
+			}
+		}
+	}
+	return title
+}
+
+func getRedirectTarget(wikitext string) (string, bool) {
+	// if the wikitext is a redirect, e.g. "#REDIRECT [[green]]", return the page it redirects to
+	re := regexp.MustCompile(`(?i)^\s*#REDIRECT\s*:?\s*\[\[([^\]|]+)(?:\|[^\]]*)?\]\]`)
+	match := re.FindStringSubmatch(wikitext)
+	if len(match) == 0 {
+		return "", false
+	}
+	return normalizeTitle(match[1]), true
+}
+
+func getWordFromTitle(title string) string {
+	// the reverse of getPageTitle - for a reconstruction, return e.g. "*raudaz"
+	if strings.HasPrefix(title, "Reconstruction:") {
+		if i := strings.Index(title, "/"); i >= 0 {
+			return "*" + title[i+1:]
+		}
+	}
+	return title
+}
@@ -0,0 +1,102 @@
+package wiktionary
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected string
+	}{
+		{"red", "red"},
+		{"Red", "Red"},
+		{"red_giant", "red giant"},
+		{"  red   giant ", "red giant"},
+		{"cafe\u0301", "caf\u00e9"},
+		{"green#Dutch", "green"},
+		{":green", "green"},
+		{"reconstruction:Proto-Germanic/raudaz", "Reconstruction:Proto-Germanic/raudaz"},
+		{"Reconstruction: Proto-Germanic/raudaz", "Reconstruction:Proto-Germanic/raudaz"},
+		{"A:B", "A:B"},
+	}
+	for _, test := range tests {
+		if title := normalizeTitle(test.title); title != test.expected {
+			t.Fatalf(`normalizeTitle(%q): expected %q, got %q`, test.title, test.expected, title)
+		}
+	}
+
+	if title := getPageTitle("*raudaz", "gem-pro"); title != "Reconstruction:Proto-Germanic/raudaz" {
+		t.Fatalf(`getPageTitle: expected %q, got %q`, "Reconstruction:Proto-Germanic/raudaz", title)
+	}
+	if word := getWordFromTitle("Reconstruction:Proto-Germanic/raudaz"); word != "*raudaz" {
+		t.Fatalf(`getWordFromTitle: expected %q, got %q`, "*raudaz", word)
+	}
+}
+
+func TestGetRedirectTarget(t *testing.T) {
+	tests := []struct {
+		wikitext string
+		target   string
+		ok       bool
+	}{
+		{"#REDIRECT [[green]]", "green", true},
+		{"#redirect:[[red_giant|red giant]]\n[[Category:Redirects]]", "red giant", true},
+		{"#REDIRECT [[green#Dutch]]", "green", true},
+		{"==English==\n#REDIRECT [[green]]", "", false},
+		{"# A redirect.", "", false},
+	}
+	for _, test := range tests {
+		target, ok := getRedirectTarget(test.wikitext)
+		if target != test.target || ok != test.ok {
+			t.Fatalf(`getRedirectTarget(%q): expected %q %v, got %q %v`, test.wikitext, test.target, test.ok, target, ok)
+		}
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	var options WiktionaryOptions
+	options.RequiredSections = Sec_All
+	options.Source = MapSource{
+		"grene":      "#REDIRECT [[grēne]]",
+		"grēne":      "#REDIRECT [[green]]",
+		"green":      testWikitext,
+		"red giant":  testWikitext,
+		"loop":       "#REDIRECT [[loop again]]",
+		"loop again": "#REDIRECT [[loop]]",
+	}
+	client := &Client{}
+
+	// redirects are followed, and the entry records where they led
+	lw, err := client.GetWordWithOptions("grene", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	if lw.Word != "grene" || lw.Title != "green" {
+		t.Fatalf(`GetWordWithOptions: expected word "grene" from page "green", got %q from %q`, lw.Word, lw.Title)
+	}
+	if lw.Meaning != "Having green as its color." {
+		t.Fatalf(`GetWordWithOptions: expected the meaning from "green", got %q`, lw.Meaning)
+	}
+
+	// titles are normalized before they are looked up
+	lw, err = client.GetWordWithOptions("red_giant", "en", options)
+	if err != nil {
+		t.Fatalf(`Error from GetWordWithOptions: %q`, err)
+	}
+	if lw.Title != "red giant" {
+		t.Fatalf(`GetWordWithOptions: expected page "red giant", got %q`, lw.Title)
+	}
+
+	// but not their first letter, as Wiktionary's titles are case-sensitive
+	_, err = client.GetWordWithOptions("Green", "en", options)
+	if !errors.Is(err, ErrPageNotFound) {
+		t.Fatalf(`GetWordWithOptions: expected ErrPageNotFound for "Green", got %q`, err)
+	}
+
+	// and a redirect loop is an error rather than a hang
+	if _, err := client.GetWordWithOptions("loop", "en", options); err == nil {
+		t.Fatalf(`GetWordWithOptions: expected an error for a redirect loop`)
+	}
+}
@@ -0,0 +1,221 @@
+package wiktionary
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// a Transport controls how requests are made to the MediaWiki API - how fast they may be sent,
+// how long each may take, and how failed requests are retried
+// the zero value uses the defaults below; a Transport may be shared between clients, in which
+// case they share its rate limit
+type Transport struct {
+	RequestsPerSecond float64       // 0 means no limit
+	MaxRetries        int           // retries after the first attempt - defaults to 3, negative for none
+	MinBackoff        time.Duration // the wait before the first retry, doubling after that - defaults to 1s
+	MaxBackoff        time.Duration // the longest wait between retries - defaults to 30s
+	Timeout           time.Duration // the time allowed for each attempt - defaults to 60s
+	MaxLag            int           // the maxlag parameter sent to the API, in seconds - defaults to 5, negative to omit it
+
+	mu   sync.Mutex
+	next time.Time // the earliest time the next request may be sent
+}
+
+const (
+	defaultMaxRetries = 3
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 30 * time.Second
+	defaultTimeout    = 60 * time.Second
+	defaultMaxLag     = 5
+)
+
+// used by any ApiSource which doesn't specify its own Transport
+var defaultTransport = &Transport{}
+
+func (t *Transport) maxRetries() int {
+	if t.MaxRetries == 0 {
+		return defaultMaxRetries
+	}
+	if t.MaxRetries < 0 {
+		return 0
+	}
+	return t.MaxRetries
+}
+
+func (t *Transport) backoff(attempt int) time.Duration {
+	// exponential backoff from MinBackoff, capped at MaxBackoff
+	minBackoff := t.MinBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultMinBackoff
+	}
+	maxBackoff := t.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	wait := minBackoff
+	for i := 0; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+func (t *Transport) timeout() time.Duration {
+	if t.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return t.Timeout
+}
+
+func (t *Transport) maxLag() int {
+	if t.MaxLag == 0 {
+		return defaultMaxLag
+	}
+	return t.MaxLag
+}
+
+func (t *Transport) wait(ctx context.Context) error {
+	// wait until the rate limit allows another request to be sent
+	if t.RequestsPerSecond <= 0 {
+		return nil
+	}
+	interval := time.Duration(float64(time.Second) / t.RequestsPerSecond)
+	t.mu.Lock()
+	now := time.Now()
+	start := t.next
+	if start.Before(now) {
+		start = now
+	}
+	t.next = start.Add(interval)
+	t.mu.Unlock()
+	return sleepContext(ctx, start.Sub(now))
+}
+
+func (t *Transport) do(ctx context.Context, httpClient *http.Client, newRequest func(context.Context) (*http.Request, error)) ([]byte, error) {
+	// send a request, retrying it if the API is overloaded or the network fails
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(ctx); err != nil {
+			return nil, err
+		}
+		body, retryAfter, err := t.attempt(ctx, httpClient, newRequest)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		// give up if the caller has, if the error won't go away by retrying, or if we've run out of retries
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !isRetryable(err) || attempt >= t.maxRetries() {
+			return nil, lastErr
+		}
+		wait := t.backoff(attempt)
+		if retryAfter >= 0 {
+			wait = retryAfter
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *Transport) attempt(ctx context.Context, httpClient *http.Client, newRequest func(context.Context) (*http.Request, error)) ([]byte, time.Duration, error) {
+	// make a single request, returning the body and any wait the server asked for (or -1 if none)
+	attemptCtx, cancel := context.WithTimeout(ctx, t.timeout())
+	defer cancel()
+	req, err := newRequest(attemptCtx)
+	if err != nil {
+		return nil, -1, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer resp.Body.Close()
+
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	// an API which is lagging reports it with a header, but otherwise a normal 200 response
+	if resp.Header.Get("MediaWiki-API-Error") == "maxlag" {
+		io.Copy(io.Discard, resp.Body)
+		return nil, retryAfter, &HttpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, MaxLag: true}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, resp.Body)
+		return nil, retryAfter, &HttpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, -1, err
+	}
+	return body, -1, nil
+}
+
+func isRetryable(err error) bool {
+	// overloading and lag are temporary, as are timeouts and most network failures
+	var statusErr *HttpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.MaxLag || statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	// except a host which doesn't exist - it won't by the next attempt either
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	// Retry-After is either a number of seconds or an HTTP date - return -1 if it's missing or invalid
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return -1
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return -1
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if date.Before(now) {
+			return 0
+		}
+		return date.Sub(now)
+	}
+	return -1
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	// sleep for the given time, or until the context is done
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,237 @@
+package wiktionary
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a stand-in API which answers each request with the next status in the list, then with 200
+func newFlakyApi(t *testing.T, statuses []int, header http.Header) (*httptest.Server, func() []url.Values) {
+	var mu sync.Mutex
+	var requests []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		n := len(requests)
+		requests = append(requests, r.Form)
+		mu.Unlock()
+		if n < len(statuses) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statuses[n])
+			w.Write([]byte("error"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []url.Values {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func fastTransport() *Transport {
+	return &Transport{MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+}
+
+func TestTransportRetriesServerErrors(t *testing.T) {
+	server, requests := newFlakyApi(t, []int{503, 502}, nil)
+	source := ApiSource{BaseUrl: server.URL, Transport: fastTransport()}
+	body, err := source.call(context.Background(), url.Values{"action": {"parse"}}, false)
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if string(body) != "ok" {
+		t.Errorf("expected body 'ok', got '%s'", body)
+	}
+	if n := len(requests()); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestTransportRetriesPost(t *testing.T) {
+	server, requests := newFlakyApi(t, []int{500}, nil)
+	source := ApiSource{BaseUrl: server.URL, Transport: fastTransport()}
+	_, err := source.call(context.Background(), url.Values{"text": {"{{m|en|red}}"}}, true)
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	// the body has to be sent again with the retry
+	for i, form := range requests() {
+		if form.Get("text") != "{{m|en|red}}" {
+			t.Errorf("request %d: expected text to be posted, got '%s'", i, form.Get("text"))
+		}
+	}
+}
+
+func TestTransportRetryAfter(t *testing.T) {
+	server, requests := newFlakyApi(t, []int{429}, http.Header{"Retry-After": {"0"}})
+	// the backoff would make the test time out, so Retry-After must be used in its place
+	transport := &Transport{MinBackoff: time.Hour}
+	source := ApiSource{BaseUrl: server.URL, Transport: transport}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := source.call(ctx, url.Values{}, false); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if n := len(requests()); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestTransportMaxLag(t *testing.T) {
+	server, requests := newFlakyApi(t, []int{200}, http.Header{"Mediawiki-Api-Error": {"maxlag"}, "Retry-After": {"0"}})
+	source := ApiSource{BaseUrl: server.URL, Transport: fastTransport()}
+	if _, err := source.call(context.Background(), url.Values{}, false); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	reqs := requests()
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(reqs))
+	}
+	if reqs[0].Get("maxlag") != "5" {
+		t.Errorf("expected maxlag=5 to be sent, got '%s'", reqs[0].Get("maxlag"))
+	}
+
+	// a negative MaxLag leaves the parameter out
+	server, requests = newFlakyApi(t, nil, nil)
+	source = ApiSource{BaseUrl: server.URL, Transport: &Transport{MaxLag: -1}}
+	source.call(context.Background(), url.Values{}, false)
+	if _, ok := requests()[0]["maxlag"]; ok {
+		t.Error("expected no maxlag parameter")
+	}
+}
+
+func TestTransportNotRetried(t *testing.T) {
+	server, requests := newFlakyApi(t, []int{404}, nil)
+	source := ApiSource{BaseUrl: server.URL, Transport: fastTransport()}
+	_, err := source.call(context.Background(), url.Values{}, false)
+	var statusErr *HttpStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 404 {
+		t.Fatalf("expected a 404 HttpStatusError, got %v", err)
+	}
+	if n := len(requests()); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestTransportGivesUp(t *testing.T) {
+	server, requests := newFlakyApi(t, []int{503, 503, 503, 503, 503}, nil)
+	transport := fastTransport()
+	transport.MaxRetries = 2
+	source := ApiSource{BaseUrl: server.URL, Transport: transport}
+	_, err := source.call(context.Background(), url.Values{}, false)
+	var statusErr *HttpStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 503 {
+		t.Fatalf("expected a 503 HttpStatusError, got %v", err)
+	}
+	if n := len(requests()); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestTransportTimeout(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		first := calls == 1
+		mu.Unlock()
+		// the first request hangs until the test ends, so only a timeout can rescue it
+		if first {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	transport := fastTransport()
+	transport.Timeout = 50 * time.Millisecond
+	source := ApiSource{BaseUrl: server.URL, Transport: transport}
+	body, err := source.call(context.Background(), url.Values{}, false)
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if string(body) != "ok" {
+		t.Errorf("expected body 'ok', got '%s'", body)
+	}
+}
+
+func TestTransportCanceled(t *testing.T) {
+	server, requests := newFlakyApi(t, []int{503, 503, 503}, nil)
+	source := ApiSource{BaseUrl: server.URL, Transport: &Transport{MinBackoff: time.Hour}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := source.call(ctx, url.Values{}, false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context's error, got %v", err)
+	}
+	if n := len(requests()); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestTransportRateLimit(t *testing.T) {
+	server, requests := newFlakyApi(t, nil, nil)
+	source := ApiSource{BaseUrl: server.URL, Transport: &Transport{RequestsPerSecond: 20}}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := source.call(context.Background(), url.Values{}, false); err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+	}
+	// the first request goes straight away, then one every 50ms
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected 5 requests to take at least 200ms, took %v", elapsed)
+	}
+	if n := len(requests()); n != 5 {
+		t.Errorf("expected 5 requests, got %d", n)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", -1},
+		{"0", 0},
+		{"120", 2 * time.Minute},
+		{"-5", -1},
+		{"soon", -1},
+		{"Sun, 01 Oct 2023 12:00:30 GMT", 30 * time.Second},
+		{"Sun, 01 Oct 2023 11:00:00 GMT", 0},
+	}
+	for _, test := range tests {
+		if got := parseRetryAfter(test.value, now); got != test.want {
+			t.Errorf("parseRetryAfter(%q): expected %v, got %v", test.value, test.want, got)
+		}
+	}
+}
+
+func TestTransportBackoff(t *testing.T) {
+	transport := &Transport{}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	for attempt, w := range want {
+		if got := transport.backoff(attempt); got != w {
+			t.Errorf("backoff(%d): expected %v, got %v", attempt, w, got)
+		}
+	}
+}
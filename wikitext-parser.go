@@ -0,0 +1,609 @@
+package wiktionary
+
+import (
+	"strconv"
+	"strings"
+)
+
+// the kinds of node in a parsed piece of wikitext
+type NodeType int
+
+const (
+	Node_Text         NodeType = iota // plain text
+	Node_Template                     // {{name|arg|name=arg}} - Text is the name
+	Node_Parameter                    // {{{1|default}}}, as found in template source - Text is the name
+	Node_Link                         // [[target|text]] - Text is the target, Children the text shown
+	Node_ExternalLink                 // [https://example.org text] - Text is the URL, Children the text shown
+	Node_Bold                         // '''text'''
+	Node_Italic                       // ''text''
+	Node_Tag                          // an HTML tag such as <span> or <sup> - Text is the lower-case tag name
+	Node_Ref                          // <ref>text</ref>
+	Node_Nowiki                       // <nowiki>text</nowiki> - Text is the text, which isn't parsed
+	Node_Comment                      // <!-- text --> - Text is the text of the comment
+)
+
+// a Node is one element of parsed wikitext - Raw holds the wikitext it was parsed from, so
+// any node can be sent to Wiktionary to be rendered as it stands
+type Node struct {
+	Type     NodeType
+	Text     string
+	Attrs    string        // for tags and refs, the attributes as written, e.g. ` name="a"`
+	Args     []TemplateArg // for templates, the arguments in the order they were written
+	Children []Node
+	Raw      string
+}
+
+// a TemplateArg is a single argument of a template - positional arguments are named by their
+// position, counting from 1 as on Wiktionary, and keep any spaces around them, while named
+// arguments are trimmed
+type TemplateArg struct {
+	Name       string
+	Positional bool
+	Value      []Node
+	Raw        string
+}
+
+// HTML tags which the parser recognizes - anything else starting with "<" is left as text
+var wikitextTags = map[string]bool{
+	"abbr": true, "b": true, "bdi": true, "big": true, "blockquote": true, "br": true, "code": true,
+	"del": true, "div": true, "em": true, "gallery": true, "hr": true, "i": true, "includeonly": true,
+	"ins": true, "math": true, "noinclude": true, "onlyinclude": true, "poem": true, "pre": true, "q": true,
+	"references": true, "rp": true, "rt": true, "ruby": true, "s": true, "small": true, "span": true,
+	"strong": true, "sub": true, "sup": true, "u": true,
+}
+
+// tags which never have any content
+var wikitextVoidTags = map[string]bool{"br": true, "hr": true}
+
+// ParseWikitext parses a piece of wikitext into a tree of nodes - anything which isn't well
+// formed, such as a template without its closing braces, is kept as text
+func ParseWikitext(text string) []Node {
+	p := wikitextParser{src: text, ends: make(map[scanKey]bool)}
+	nodes, _ := p.parseNodes(nil, 0)
+	return nodes
+}
+
+// formatting which is open while parsing, and can be closed by a run of quotes
+const (
+	format_none = iota
+	format_italic
+	format_bold
+)
+
+type wikitextParser struct {
+	src  string
+	pos  int
+	memo map[parseKey]parseResult
+	last map[string]int   // where each closing string last appears, e.g. "}}"
+	ends map[scanKey]bool // where a scan inside a template or link runs to the end of the text
+}
+
+// a scan for the given stops, from the given position
+type scanKey struct {
+	pos   int
+	stops string
+}
+
+// templates, parameters, links and tags are parsed with stops of their own, so whatever is
+// around them, the result at a given position is always the same - each is remembered, so that
+// an unclosed construct isn't parsed again by every attempt at the constructs around it
+type parseKey struct {
+	pos  int
+	kind byte
+}
+
+type parseResult struct {
+	node Node
+	ok   bool
+	end  int
+}
+
+func (p *wikitextParser) closedLater(close string) bool {
+	// whether the closing string appears after the current position at all - if not, there's no
+	// need to parse what follows to find that a construct isn't closed
+	if p.last == nil {
+		p.last = make(map[string]int)
+	}
+	last, found := p.last[close]
+	if !found {
+		last = strings.LastIndex(p.src, close)
+		if strings.HasPrefix(close, "</") {
+			// tag names are case-insensitive
+			last = lastIndexFold(p.src, close)
+		}
+		p.last[close] = last
+	}
+	return last >= p.pos
+}
+
+func (p *wikitextParser) parseOnce(kind byte, parse func() (Node, bool)) (Node, bool) {
+	key := parseKey{p.pos, kind}
+	if result, found := p.memo[key]; found {
+		if result.ok {
+			p.pos = result.end
+		}
+		return result.node, result.ok
+	}
+	node, ok := parse()
+	if p.memo == nil {
+		p.memo = make(map[parseKey]parseResult)
+	}
+	p.memo[key] = parseResult{node: node, ok: ok, end: p.pos}
+	return node, ok
+}
+
+func (p *wikitextParser) parseNodes(stops []string, format int) ([]Node, string) {
+	// parse nodes until one of the stops is reached at this level, returning the stop found
+	// (which is not consumed), or "" at the end of the text
+	var nodes []Node
+	textStart := p.pos
+	flush := func() {
+		if p.pos > textStart {
+			text := p.src[textStart:p.pos]
+			// merge with any text just before, e.g. left by a construct which wasn't closed
+			if n := len(nodes); n > 0 && nodes[n-1].Type == Node_Text {
+				nodes[n-1].Text += text
+				nodes[n-1].Raw += text
+			} else {
+				nodes = append(nodes, Node{Type: Node_Text, Text: text, Raw: text})
+			}
+		}
+	}
+
+	// the scans inside templates, parameters and links fail if they reach the end of the text
+	// without a stop - and any other scan for the same stops which gets to a point such a scan
+	// resumed from will too, so it can stop there rather than going over the same text again
+	failsAtEnd := format == format_none && len(stops) > 0 && !strings.HasPrefix(stops[0], "</")
+	stopsKey := strings.Join(stops, "\x00")
+	resumes := []int{p.pos}
+	reachedEnd := func() ([]Node, string) {
+		if failsAtEnd {
+			for _, pos := range resumes {
+				p.ends[scanKey{pos, stopsKey}] = true
+			}
+		}
+		flush()
+		return nodes, ""
+	}
+
+	for p.pos < len(p.src) {
+		rest := p.src[p.pos:]
+		for _, stop := range stops {
+			if strings.HasPrefix(rest, stop) || (strings.HasPrefix(stop, "</") && hasPrefixFold(rest, stop)) {
+				flush()
+				return nodes, stop
+			}
+		}
+		// formatting runs to the end of the line at most
+		if format != format_none && rest[0] == '\n' {
+			flush()
+			return nodes, "\n"
+		}
+
+		var node Node
+		var ok bool
+		tried := true
+		start := p.pos
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			node, ok = p.parseComment(), true
+		case strings.HasPrefix(rest, "{{{"):
+			node, ok = p.parseOnce('p', p.parseParameter)
+			if !ok {
+				node, ok = p.parseOnce('t', p.parseTemplate)
+			}
+		case strings.HasPrefix(rest, "{{"):
+			node, ok = p.parseOnce('t', p.parseTemplate)
+		case strings.HasPrefix(rest, "[["):
+			node, ok = p.parseOnce('l', p.parseLink)
+		case rest[0] == '[':
+			node, ok = p.parseOnce('[', p.parseExternalLink)
+		case rest[0] == '<':
+			node, ok = p.parseOnce('<', p.parseTag)
+		case strings.HasPrefix(rest, "''"):
+			quotes := countQuotes(rest)
+			// a run of quotes closes the formatting which is open, if it can
+			if (format == format_italic && (quotes == 2 || quotes >= 5)) ||
+				(format == format_bold && (quotes == 3 || quotes >= 5)) {
+				flush()
+				return nodes, "'"
+			}
+			node, ok = p.parseFormatting(stops, quotes)
+		default:
+			tried = false
+		}
+
+		if !ok {
+			// not the start of anything, so it's text
+			p.pos++
+			if tried && failsAtEnd {
+				if p.ends[scanKey{p.pos, stopsKey}] {
+					p.pos = len(p.src)
+					return reachedEnd()
+				}
+				resumes = append(resumes, p.pos)
+			}
+			continue
+		}
+		end := p.pos
+		p.pos = start
+		flush()
+		p.pos = end
+		nodes = append(nodes, node)
+		textStart = p.pos
+	}
+	return reachedEnd()
+}
+
+func (p *wikitextParser) parseComment() Node {
+	// <!-- comment --> - an unclosed comment runs to the end of the text
+	start := p.pos
+	end := strings.Index(p.src[start+4:], "-->")
+	if end < 0 {
+		p.pos = len(p.src)
+		return Node{Type: Node_Comment, Text: p.src[start+4:], Raw: p.src[start:]}
+	}
+	p.pos = start + 4 + end + 3
+	return Node{Type: Node_Comment, Text: p.src[start+4 : start+4+end], Raw: p.src[start:p.pos]}
+}
+
+func (p *wikitextParser) parseTemplate() (Node, bool) {
+	// {{name|positional|name=value}} - the name and arguments can hold further templates
+	start := p.pos
+	if !p.closedLater("}}") {
+		return Node{}, false
+	}
+	p.pos += 2
+	nameNodes, stop := p.parseNodes([]string{"|", "}}"}, format_none)
+	if stop == "" {
+		p.pos = start
+		return Node{}, false
+	}
+	node := Node{Type: Node_Template, Text: strings.TrimSpace(rawText(nameNodes))}
+	// when a template inside this one isn't closed, its arguments become this one's - and so
+	// also those of every template around it, so whether the arguments from a given "|" run to
+	// the end of the text is remembered, rather than finding it out again for each of them
+	position := 1
+	var argStarts []int
+	for stop == "|" {
+		p.pos++
+		if p.ends[scanKey{p.pos, "args"}] {
+			stop = ""
+		} else {
+			argStarts = append(argStarts, p.pos)
+			var arg TemplateArg
+			arg, stop = p.parseTemplateArg(&position)
+			node.Args = append(node.Args, arg)
+		}
+		if stop == "" {
+			for _, pos := range argStarts {
+				p.ends[scanKey{pos, "args"}] = true
+			}
+			p.pos = start
+			return Node{}, false
+		}
+	}
+	p.pos += 2
+	node.Raw = p.src[start:p.pos]
+	return node, true
+}
+
+func (p *wikitextParser) parseTemplateArg(position *int) (TemplateArg, string) {
+	// an argument is named if it has an "=" outside any nested template or link
+	valueStart := p.pos
+	value, stop := p.parseNodes([]string{"|", "}}", "="}, format_none)
+	if stop == "=" {
+		name := strings.TrimSpace(p.src[valueStart:p.pos])
+		p.pos++
+		valueStart = p.pos
+		value, stop = p.parseNodes([]string{"|", "}}"}, format_none)
+		raw := p.src[valueStart:p.pos]
+		return TemplateArg{Name: name, Value: trimNodes(value), Raw: strings.TrimSpace(raw)}, stop
+	}
+	arg := TemplateArg{Name: strconv.Itoa(*position), Positional: true, Value: value, Raw: p.src[valueStart:p.pos]}
+	*position++
+	return arg, stop
+}
+
+func (p *wikitextParser) parseParameter() (Node, bool) {
+	// {{{name|default}}}
+	start := p.pos
+	if !p.closedLater("}}}") {
+		return Node{}, false
+	}
+	p.pos += 3
+	nameNodes, stop := p.parseNodes([]string{"|", "}}}"}, format_none)
+	if stop == "" {
+		p.pos = start
+		return Node{}, false
+	}
+	node := Node{Type: Node_Parameter, Text: strings.TrimSpace(rawText(nameNodes))}
+	if stop == "|" {
+		p.pos++
+		node.Children, stop = p.parseNodes([]string{"}}}"}, format_none)
+		if stop == "" {
+			p.pos = start
+			return Node{}, false
+		}
+	}
+	p.pos += 3
+	node.Raw = p.src[start:p.pos]
+	return node, true
+}
+
+func (p *wikitextParser) parseLink() (Node, bool) {
+	// [[target|text]] - a link's target can't run over a line
+	start := p.pos
+	if !p.closedLater("]]") {
+		return Node{}, false
+	}
+	p.pos += 2
+	targetNodes, stop := p.parseNodes([]string{"|", "]]", "\n"}, format_none)
+	if stop == "" || stop == "\n" {
+		p.pos = start
+		return Node{}, false
+	}
+	node := Node{Type: Node_Link, Text: strings.TrimSpace(rawText(targetNodes))}
+	if stop == "|" {
+		p.pos++
+		node.Children, stop = p.parseNodes([]string{"]]"}, format_none)
+		if stop == "" {
+			p.pos = start
+			return Node{}, false
+		}
+	}
+	p.pos += 2
+	node.Raw = p.src[start:p.pos]
+	return node, true
+}
+
+func (p *wikitextParser) parseExternalLink() (Node, bool) {
+	// [https://example.org text] - only recognized for URLs, and on a single line
+	start := p.pos
+	rest := p.src[start+1:]
+	isUrl := false
+	for _, scheme := range []string{"http://", "https://", "ftp://", "//", "mailto:"} {
+		if hasPrefixFold(rest, scheme) {
+			isUrl = true
+			break
+		}
+	}
+	if !isUrl {
+		return Node{}, false
+	}
+	urlEnd := strings.IndexAny(rest, " ]\n")
+	if urlEnd < 0 || rest[urlEnd] == '\n' {
+		return Node{}, false
+	}
+	node := Node{Type: Node_ExternalLink, Text: rest[:urlEnd]}
+	p.pos = start + 1 + urlEnd
+	if rest[urlEnd] == ' ' {
+		p.pos++
+		var stop string
+		node.Children, stop = p.parseNodes([]string{"]", "\n"}, format_none)
+		if stop != "]" {
+			p.pos = start
+			return Node{}, false
+		}
+	}
+	p.pos++
+	node.Raw = p.src[start:p.pos]
+	return node, true
+}
+
+func (p *wikitextParser) parseTag() (Node, bool) {
+	// <nowiki>, <ref> or another known HTML tag, with its content up to the closing tag
+	start := p.pos
+	rest := p.src[start:]
+	nameEnd := 1
+	for nameEnd < len(rest) && isTagNameChar(rest[nameEnd]) {
+		nameEnd++
+	}
+	name := strings.ToLower(rest[1:nameEnd])
+	if name != "nowiki" && name != "ref" && !wikitextTags[name] {
+		return Node{}, false
+	}
+	// the name must end the tag or be followed by its attributes, so e.g. "<q:dated>" isn't a tag
+	if nameEnd == len(rest) || !strings.ContainsRune(" \t\n/>", rune(rest[nameEnd])) {
+		return Node{}, false
+	}
+	tagEnd := strings.IndexByte(rest, '>')
+	if tagEnd < 0 {
+		return Node{}, false
+	}
+	attrs := rest[nameEnd:tagEnd]
+	selfClosing := strings.HasSuffix(attrs, "/")
+	attrs = strings.TrimSuffix(attrs, "/")
+	p.pos = start + tagEnd + 1
+
+	nodeType := Node_Tag
+	switch name {
+	case "ref":
+		nodeType = Node_Ref
+	case "nowiki":
+		nodeType = Node_Nowiki
+	}
+	node := Node{Type: nodeType, Text: name, Attrs: attrs}
+	if selfClosing || wikitextVoidTags[name] {
+		if nodeType == Node_Nowiki {
+			node.Text = ""
+		}
+		node.Raw = p.src[start:p.pos]
+		return node, true
+	}
+
+	closeTag := "</" + name
+	if nodeType == Node_Nowiki {
+		// the content of a nowiki tag isn't parsed at all
+		end := indexFold(p.src[p.pos:], closeTag)
+		if end < 0 {
+			end = len(p.src) - p.pos
+		}
+		node.Text = p.src[p.pos : p.pos+end]
+		p.pos += end
+	} else if p.closedLater(closeTag) {
+		node.Children, _ = p.parseNodes([]string{closeTag}, format_none)
+	}
+	// an unclosed tag is left with no content, so it can't swallow the end of a template around it
+	if hasPrefixFold(p.src[p.pos:], closeTag) {
+		if end := strings.IndexByte(p.src[p.pos:], '>'); end >= 0 {
+			p.pos += end + 1
+		}
+	}
+	node.Raw = p.src[start:p.pos]
+	return node, true
+}
+
+func (p *wikitextParser) parseFormatting(stops []string, quotes int) (Node, bool) {
+	// ''italic'' or '''bold''' - '''''both''''' is bold around italic, and any quotes beyond
+	// those are apostrophes, e.g. four quotes are an apostrophe followed by bold
+	if quotes == 4 || quotes > 5 {
+		return Node{}, false
+	}
+	start := p.pos
+	nodeType, format, width := Node_Italic, format_italic, 2
+	if quotes >= 3 {
+		nodeType, format, width = Node_Bold, format_bold, 3
+	}
+	p.pos += width
+	children, stop := p.parseNodes(stops, format)
+	if stop == "'" {
+		p.pos += width
+	}
+	return Node{Type: nodeType, Children: children, Raw: p.src[start:p.pos]}, true
+}
+
+func countQuotes(text string) int {
+	n := 0
+	for n < len(text) && text[n] == '\'' {
+		n++
+	}
+	return n
+}
+
+func isTagNameChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func hasPrefixFold(text string, prefix string) bool {
+	// whether text starts with the ASCII prefix, ignoring case - only ASCII letters are folded,
+	// so a match is always the same number of bytes as the prefix
+	if len(text) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if lowerAscii(text[i]) != lowerAscii(prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func indexFold(text string, substr string) int {
+	// the byte offset in text of the first ASCII substr, ignoring case, without lowering a copy
+	// of text - lowering can change the length of other characters, e.g. "Ⱥ"
+	for i := 0; i+len(substr) <= len(text); i++ {
+		if hasPrefixFold(text[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func lastIndexFold(text string, substr string) int {
+	// as indexFold, but the last one
+	for i := len(text) - len(substr); i >= 0; i-- {
+		if hasPrefixFold(text[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func lowerAscii(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func rawText(nodes []Node) string {
+	// the wikitext the nodes were parsed from
+	var text strings.Builder
+	for _, node := range nodes {
+		text.WriteString(node.Raw)
+	}
+	return text.String()
+}
+
+func trimNodes(nodes []Node) []Node {
+	// trim the spaces from the start and end of a list of nodes, as MediaWiki does for named arguments
+	if len(nodes) > 0 && nodes[0].Type == Node_Text {
+		nodes[0].Text = strings.TrimLeft(nodes[0].Text, " \t\n")
+		nodes[0].Raw = nodes[0].Text
+	}
+	if n := len(nodes); n > 0 && nodes[n-1].Type == Node_Text {
+		nodes[n-1].Text = strings.TrimRight(nodes[n-1].Text, " \t\n")
+		nodes[n-1].Raw = nodes[n-1].Text
+	}
+	return nodes
+}
+
+// Arg returns the value of a template argument as written, and whether the template has it
+// - positional arguments are named "1", "2" and so on
+func (n *Node) Arg(name string) (string, bool) {
+	for _, arg := range n.Args {
+		if arg.Name == name {
+			return arg.Raw, true
+		}
+	}
+	return "", false
+}
+
+// ArgMap returns a template's arguments as a map from name to value - if an argument is given
+// more than once, the last one wins, as on Wiktionary
+func (n *Node) ArgMap() map[string]string {
+	args := make(map[string]string, len(n.Args))
+	for _, arg := range n.Args {
+		args[arg.Name] = arg.Raw
+	}
+	return args
+}
+
+// Templates returns every template in the nodes, including those nested inside other
+// templates, links and tags, in the order they start in the text
+func Templates(nodes []Node) []*Node {
+	var templates []*Node
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Type == Node_Template {
+			templates = append(templates, node)
+			for j := range node.Args {
+				templates = append(templates, Templates(node.Args[j].Value)...)
+			}
+		}
+		templates = append(templates, Templates(node.Children)...)
+	}
+	return templates
+}
+
+// PlainText returns the text of the nodes with the markup removed - templates, comments and
+// refs are left out, links show their text and nowiki its content
+func PlainText(nodes []Node) string {
+	var text strings.Builder
+	for _, node := range nodes {
+		switch node.Type {
+		case Node_Text, Node_Nowiki:
+			text.WriteString(node.Text)
+		case Node_Link:
+			if len(node.Children) > 0 {
+				text.WriteString(PlainText(node.Children))
+			} else {
+				text.WriteString(node.Text)
+			}
+		case Node_ExternalLink, Node_Bold, Node_Italic, Node_Tag:
+			text.WriteString(PlainText(node.Children))
+		}
+	}
+	return text.String()
+}
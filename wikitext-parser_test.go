@@ -0,0 +1,172 @@
+package wiktionary
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTemplates(t *testing.T) {
+	// a nested template no longer cuts off its parent at the first "}}"
+	nodes := ParseWikitext("From {{der|en|la|{{m|la|x}}|t=a [[link|text]]}}.")
+	if len(nodes) != 3 || nodes[0].Text != "From " || nodes[2].Text != "." {
+		t.Fatalf(`ParseWikitext: expected text, a template and text, got %+v`, nodes)
+	}
+	der := nodes[1]
+	if der.Type != Node_Template || der.Text != "der" || len(der.Args) != 4 {
+		t.Fatalf(`ParseWikitext: expected the der template with 4 arguments, got %+v`, der)
+	}
+	if der.Raw != "{{der|en|la|{{m|la|x}}|t=a [[link|text]]}}" {
+		t.Fatalf(`ParseWikitext: expected the whole template as its raw text, got %q`, der.Raw)
+	}
+	if val, _ := der.Arg("3"); val != "{{m|la|x}}" {
+		t.Fatalf(`Arg: expected the nested template as argument 3, got %q`, val)
+	}
+	// the "|" inside a link doesn't split the argument
+	if val, _ := der.Arg("t"); val != "a [[link|text]]" {
+		t.Fatalf(`Arg: expected the link in argument t, got %q`, val)
+	}
+
+	templates := Templates(nodes)
+	if len(templates) != 2 || templates[0].Text != "der" || templates[1].Text != "m" {
+		t.Fatalf(`Templates: expected der then m, got %d templates`, len(templates))
+	}
+	if val, _ := templates[1].Arg("2"); val != "x" {
+		t.Fatalf(`Arg: expected "x" in the nested template, got %q`, val)
+	}
+
+	// positional arguments are numbered without counting the named ones, and keep their spaces
+	args := ParseWikitext("{{de-noun|n|Buchs|gen2= Buches |Bücher| Büchlein}}")[0].ArgMap()
+	expected := map[string]string{"1": "n", "2": "Buchs", "gen2": "Buches", "3": "Bücher", "4": " Büchlein"}
+	for key, val := range expected {
+		if args[key] != val {
+			t.Fatalf(`ArgMap: expected %q for %q, got %q`, val, key, args[key])
+		}
+	}
+}
+
+func TestParseWikitextNodes(t *testing.T) {
+	tests := []struct {
+		input    string
+		nodeType NodeType
+		text     string
+		plain    string
+	}{
+		{"[[red]]", Node_Link, "red", "red"},
+		{"[[w:Red|the ''colour'']]", Node_Link, "w:Red", "the colour"},
+		{"[https://example.org an example]", Node_ExternalLink, "https://example.org", "an example"},
+		{"'''bold'''", Node_Bold, "", "bold"},
+		{"''italic''", Node_Italic, "", "italic"},
+		{"<span class=\"x\">text</span>", Node_Tag, "span", "text"},
+		{"<ref name=\"a\">a {{m|en|ref}}</ref>", Node_Ref, "ref", ""},
+		{"<nowiki>{{not a template}}</nowiki>", Node_Nowiki, "{{not a template}}", "{{not a template}}"},
+		{"<!-- a {{comment}} -->", Node_Comment, " a {{comment}} ", ""},
+		{"{{{1|default}}}", Node_Parameter, "1", ""},
+	}
+	for _, test := range tests {
+		nodes := ParseWikitext(test.input)
+		if len(nodes) != 1 || nodes[0].Type != test.nodeType || nodes[0].Text != test.text {
+			t.Fatalf(`ParseWikitext(%q): expected a single node of type %d with text %q, got %+v`, test.input, test.nodeType, test.text, nodes)
+		}
+		if nodes[0].Raw != test.input {
+			t.Fatalf(`ParseWikitext(%q): expected the input as the raw text, got %q`, test.input, nodes[0].Raw)
+		}
+		if plain := PlainText(nodes); plain != test.plain {
+			t.Fatalf(`PlainText(%q): expected %q, got %q`, test.input, test.plain, plain)
+		}
+	}
+
+	// bold around italic, and the formatting closes at the end of the line
+	nodes := ParseWikitext("'''''both''''' and ''open\nnext")
+	if nodes[0].Type != Node_Bold || nodes[0].Children[0].Type != Node_Italic || nodes[2].Type != Node_Italic {
+		t.Fatalf(`ParseWikitext: expected bold italic text then italic text, got %+v`, nodes)
+	}
+	if plain := PlainText(nodes); plain != "both and open\nnext" {
+		t.Fatalf(`PlainText: expected the text without formatting, got %q`, plain)
+	}
+}
+
+func TestParseMalformedWikitext(t *testing.T) {
+	// anything which isn't closed is kept as text
+	tests := []string{
+		"{{der|en|la",
+		"[[red",
+		"[[red\n]]",
+		"a < b",
+		"}} and ]]",
+		"[not a link]",
+	}
+	for _, test := range tests {
+		nodes := ParseWikitext(test)
+		if len(nodes) != 1 || nodes[0].Type != Node_Text || nodes[0].Text != test {
+			t.Fatalf(`ParseWikitext(%q): expected a single text node, got %+v`, test, nodes)
+		}
+	}
+
+	// but a template after an unclosed one is still found
+	nodes := ParseWikitext("{{a|b {{m|en|x}}")
+	if templates := Templates(nodes); len(templates) != 1 || templates[0].Text != "m" {
+		t.Fatalf(`ParseWikitext: expected the m template after an unclosed one, got %+v`, nodes)
+	}
+}
+
+func TestParseUnclosedQuickly(t *testing.T) {
+	// each unclosed construct is only parsed once, rather than again by every one around it
+	// - even when the last of them is closed, so the end of the text has to be reached to find
+	// that the rest aren't
+	for _, open := range []string{"{{a|", "[[a|", "{{{a|", "[https://a ", "{{a|[[b|", "<span>a"} {
+		for _, close := range []string{"", "}}]]"} {
+			text := strings.Repeat(open, 50) + close
+			done := make(chan []Node)
+			go func() { done <- ParseWikitext(text) }()
+			select {
+			case nodes := <-done:
+				if rawText(nodes) != text {
+					t.Fatalf(`ParseWikitext(%q x 50 + %q): expected all the text back, got %q`, open, close, rawText(nodes))
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf(`ParseWikitext(%q x 50 + %q): took too long`, open, close)
+			}
+		}
+	}
+	if nodes := ParseWikitext(strings.Repeat("{{a|", 50)); len(nodes) != 1 || nodes[0].Type != Node_Text {
+		t.Fatalf(`ParseWikitext: expected unclosed templates to be a single text node, got %+v`, nodes)
+	}
+
+	// and a valid template inside an unclosed one is still found
+	nodes := ParseWikitext(strings.Repeat("{{a|", 50) + "{{m|en|x}}")
+	if templates := Templates(nodes); len(templates) != 1 || templates[0].Text != "m" {
+		t.Fatalf(`ParseWikitext: expected the m template after the unclosed ones, got %d templates`, len(templates))
+	}
+}
+
+func TestParseTagsFoldCase(t *testing.T) {
+	// closing tags match whatever their case, and text which changes length when lowercased
+	// is kept as it is
+	content := strings.Repeat("Ⱥ", 10) + "İK"
+	nodes := ParseWikitext("<nowiki>" + content + "</NoWiki> x")
+	if len(nodes) != 2 || nodes[0].Type != Node_Nowiki || nodes[0].Text != content || nodes[1].Text != " x" {
+		t.Fatalf(`ParseWikitext: expected nowiki holding %q then text, got %+v`, content, nodes)
+	}
+	nodes = ParseWikitext("<ref>" + content + "</REF>")
+	if len(nodes) != 1 || nodes[0].Type != Node_Ref || PlainText(nodes[0].Children) != content {
+		t.Fatalf(`ParseWikitext: expected a ref holding %q, got %+v`, content, nodes)
+	}
+}
+
+func TestParseTagsInTemplates(t *testing.T) {
+	// inline modifiers look like tags, but aren't
+	nodes := ParseWikitext("{{col3|en|red<q:dated>|green}}")
+	if len(nodes) != 1 || nodes[0].Type != Node_Template || len(nodes[0].Args) != 3 {
+		t.Fatalf(`ParseWikitext: expected a template with 3 arguments, got %+v`, nodes)
+	}
+	if val, _ := nodes[0].Arg("2"); val != "red<q:dated>" {
+		t.Fatalf(`Arg: expected "red<q:dated>", got %q`, val)
+	}
+
+	// and an unclosed tag doesn't take the end of the template with it
+	nodes = ParseWikitext("{{m|en|x<span>}} y")
+	if len(nodes) != 2 || nodes[0].Type != Node_Template || nodes[1].Text != " y" {
+		t.Fatalf(`ParseWikitext: expected a template then text, got %+v`, nodes)
+	}
+}